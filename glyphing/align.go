@@ -0,0 +1,161 @@
+package glyphing
+
+import "github.com/npillmayer/css-box-layout/layout"
+
+// Text alignment (CSS Text 3 §6) is a post-breaking step: the free space of
+// each line box is distributed after line breaking, moving glyph fragments
+// and, for justification, widening them.
+//
+// Trailing word separators hang: they take no part in measuring a line and
+// receive no justification space. If a line's content overflows, it is start
+// aligned.
+
+// AlignLines aligns the fragments of the line boxes of a block container
+// according to its text-align, text-align-last and text-justify. buffers holds
+// the shaped glyphs referenced by the fragments.
+func AlignLines(lines []LineBox, block *layout.ComputedStyle, buffers map[layout.NodeID]GlyphBuffer) {
+	var style layout.ComputedStyle
+	if block != nil {
+		style = *block
+	}
+	for i := range lines {
+		align := style.TextAlign
+		if lines[i].ForcedBreak || i == len(lines)-1 {
+			align = lastLineAlign(style.TextAlign, style.TextAlignLast)
+		}
+		alignLine(&lines[i], physicalAlign(align, style.Direction), style.TextJustify, buffers)
+	}
+}
+
+func lastLineAlign(align, last layout.TextAlign) layout.TextAlign {
+	if last != layout.TextAlignAuto {
+		return last
+	}
+	if align == layout.TextAlignJustify {
+		return layout.TextAlignStart
+	}
+	return align
+}
+
+// physicalAlign maps start and end to left or right.
+func physicalAlign(align layout.TextAlign, dir layout.Direction) layout.TextAlign {
+	rtl := dir == layout.DirectionRTL
+	switch align {
+	case layout.TextAlignAuto, layout.TextAlignStart:
+		if rtl {
+			return layout.TextAlignRight
+		}
+		return layout.TextAlignLeft
+	case layout.TextAlignEnd:
+		if rtl {
+			return layout.TextAlignLeft
+		}
+		return layout.TextAlignRight
+	}
+	return align
+}
+
+func alignLine(line *LineBox, align layout.TextAlign, justify layout.TextJustify, buffers map[layout.NodeID]GlyphBuffer) {
+	if len(line.Frags) == 0 {
+		return
+	}
+	opps := findOpportunities(line.Frags, buffers, justify == layout.TextJustifyInterCharacter)
+	free := line.Frame.W - (lineContentEnd(line.Frags) - opps.hang - line.Frame.X)
+	if free <= 0 {
+		return
+	}
+	switch align {
+	case layout.TextAlignRight:
+		shiftFrags(line.Frags, free)
+	case layout.TextAlignCenter:
+		shiftFrags(line.Frags, free/2)
+	case layout.TextAlignJustify:
+		if justify != layout.TextJustifyNone && opps.total > 0 {
+			justifyFrags(line.Frags, opps, free/float32(opps.total), justify == layout.TextJustifyInterCharacter)
+		}
+	}
+}
+
+// opportunities counts the justification opportunities per fragment.
+type opportunities struct {
+	perFrag []int
+	total   int
+	hang    float32 // width of hanging trailing word separators
+}
+
+// findOpportunities finds justification opportunities: word separators, or
+// cluster ends if clusters is set. Hanging separators at the end of the line
+// and the end of the last cluster are no opportunities.
+func findOpportunities(frags []GlyphFragment, buffers map[layout.NodeID]GlyphBuffer, clusters bool) opportunities {
+	opps := opportunities{perFrag: make([]int, len(frags))}
+	hanging := true // still scanning trailing separators
+	lastCluster := true
+	for i := len(frags) - 1; i >= 0; i-- {
+		glyphs := fragGlyphs(frags[i], buffers)
+		for j := len(glyphs) - 1; j >= 0; j-- {
+			g := glyphs[j]
+			sep := g.Flags&GlyphWordSeparator != 0
+			if hanging && sep {
+				opps.hang += g.Advance
+				continue
+			}
+			hanging = false
+			var opp bool
+			if clusters {
+				end := j == len(glyphs)-1 || glyphs[j+1].Cluster != g.Cluster
+				opp = end && !lastCluster
+				if end {
+					lastCluster = false
+				}
+			} else {
+				opp = sep
+			}
+			if opp {
+				opps.perFrag[i]++
+				opps.total++
+			}
+		}
+	}
+	return opps
+}
+
+func fragGlyphs(f GlyphFragment, buffers map[layout.NodeID]GlyphBuffer) []Glyph {
+	switch f.Kind {
+	case FragGlyphSynthetic:
+		return f.Synth.Glyphs
+	case FragAtomic:
+		return nil
+	}
+	glyphs := buffers[f.Slice.BufferOwner].Glyphs
+	if f.Slice.From < 0 || f.Slice.To > len(glyphs) || f.Slice.From > f.Slice.To {
+		return nil
+	}
+	return glyphs[f.Slice.From:f.Slice.To]
+}
+
+func lineContentEnd(frags []GlyphFragment) float32 {
+	end := frags[0].Frame.X
+	for _, f := range frags {
+		end = max(end, f.Frame.X+f.Frame.W)
+	}
+	return end
+}
+
+func shiftFrags(frags []GlyphFragment, dx float32) {
+	for i := range frags {
+		frags[i].Frame.X += dx
+	}
+}
+
+func justifyFrags(frags []GlyphFragment, opps opportunities, space float32, clusters bool) {
+	var dx float32
+	for i := range frags {
+		f := &frags[i]
+		f.Frame.X += dx
+		if n := opps.perFrag[i]; n > 0 {
+			f.JustifySpace, f.JustifyCount, f.JustifyClusters = space, n, clusters
+			f.Frame.W += space * float32(n)
+			dx += space * float32(n)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// wordGlyphs shapes s with 10px per byte; spaces become word separators.
+func wordGlyphs(s string) []Glyph {
+	glyphs := make([]Glyph, len(s))
+	for i := range s {
+		glyphs[i] = Glyph{Advance: 10, Cluster: text.TextPos(i)}
+		if s[i] == ' ' {
+			glyphs[i].Flags = GlyphWordSeparator
+		}
+	}
+	return glyphs
+}
+
+// testLine puts two fragments "ab c" and "d " from one buffer on a 100px line.
+func testLine() ([]LineBox, map[layout.NodeID]GlyphBuffer) {
+	buffers := map[layout.NodeID]GlyphBuffer{1: {Glyphs: wordGlyphs("ab cd ")}}
+	line := func() LineBox {
+		return LineBox{
+			Frame: layout.Rect{X: 5, W: 100},
+			Frags: []GlyphFragment{
+				{Frame: layout.Rect{X: 5, W: 40}, Slice: GlyphSlice{BufferOwner: 1, From: 0, To: 4}},
+				{Frame: layout.Rect{X: 45, W: 20}, Slice: GlyphSlice{BufferOwner: 1, From: 4, To: 6}},
+			},
+		}
+	}
+	return []LineBox{line(), line()}, buffers
+}
+
+func TestAlignLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		style layout.ComputedStyle
+		first []float32 // X of the fragments of the first line
+		last  []float32 // X of the fragments of the last line
+	}{
+		{name: "start", first: []float32{5, 45}, last: []float32{5, 45}},
+		{name: "right", style: layout.ComputedStyle{TextAlign: layout.TextAlignRight},
+			first: []float32{55, 95}, last: []float32{55, 95}},
+		{name: "rtl_start", style: layout.ComputedStyle{Direction: layout.DirectionRTL},
+			first: []float32{55, 95}, last: []float32{55, 95}},
+		{name: "rtl_end", style: layout.ComputedStyle{Direction: layout.DirectionRTL, TextAlign: layout.TextAlignEnd},
+			first: []float32{5, 45}, last: []float32{5, 45}},
+		{name: "center", style: layout.ComputedStyle{TextAlign: layout.TextAlignCenter},
+			first: []float32{30, 70}, last: []float32{30, 70}},
+		{name: "justify", style: layout.ComputedStyle{TextAlign: layout.TextAlignJustify},
+			first: []float32{5, 95}, last: []float32{5, 45}},
+		{name: "justify_last_center", style: layout.ComputedStyle{TextAlign: layout.TextAlignJustify, TextAlignLast: layout.TextAlignCenter},
+			first: []float32{5, 95}, last: []float32{30, 70}},
+		{name: "justify_chars", style: layout.ComputedStyle{TextAlign: layout.TextAlignJustify, TextJustify: layout.TextJustifyInterCharacter},
+			first: []float32{5, 95}, last: []float32{5, 45}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, buffers := testLine()
+			AlignLines(lines, &tt.style, buffers)
+			for i, want := range [][]float32{tt.first, tt.last} {
+				for j, f := range lines[i].Frags {
+					if f.Frame.X != want[j] {
+						t.Errorf("line %d, fragment %d: expected x=%v, got %v", i, j, want[j], f.Frame.X)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAlignLines_JustifySpace(t *testing.T) {
+	lines, buffers := testLine()
+	AlignLines(lines[:1], &layout.ComputedStyle{
+		TextAlign:     layout.TextAlignJustify,
+		TextAlignLast: layout.TextAlignJustify,
+	}, buffers)
+	// Free space 100-50 (the trailing space hangs) goes to the single inner separator.
+	f := lines[0].Frags
+	if f[0].JustifyCount != 1 || f[0].JustifySpace != 50 || f[0].Frame.W != 90 {
+		t.Fatalf("unexpected justification of first fragment: %+v", f[0])
+	}
+	if f[1].JustifyCount != 0 || f[1].Frame.X != 95 {
+		t.Fatalf("expected hanging separator without justification, got %+v", f[1])
+	}
+}
+
+func TestAlignLines_JustifyClusters(t *testing.T) {
+	lines, buffers := testLine()
+	AlignLines(lines[:1], &layout.ComputedStyle{
+		TextAlign:     layout.TextAlignJustify,
+		TextAlignLast: layout.TextAlignJustify,
+		TextJustify:   layout.TextJustifyInterCharacter,
+	}, buffers)
+	// Opportunities after "a", "b", " " and "c"; "d" ends the line.
+	f := lines[0].Frags[0]
+	if !f.JustifyClusters || f.JustifyCount != 4 || f.JustifySpace != 12.5 {
+		t.Fatalf("unexpected inter-character justification: %+v", f)
+	}
+}
@@ -0,0 +1,111 @@
+package glyphing
+
+import (
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// Visual reordering (UAX #9 §3.4). Line breaking works on logical order;
+// each fragment carries the resolved embedding level of its content, see
+// text.BidiLevels. Fragments must not mix levels.
+
+// ReorderLines reorders the fragments of the line boxes of a block container
+// from logical to visual order. Call it after IndentLines and before
+// AlignLines.
+func ReorderLines(lines []LineBox, block *layout.ComputedStyle, buffers map[layout.NodeID]GlyphBuffer) {
+	var para text.Level
+	if block != nil && block.Direction == layout.DirectionRTL {
+		para = 1
+	}
+	for i := range lines {
+		ReorderLine(&lines[i], para, buffers)
+	}
+}
+
+// ReorderLine reorders the fragments of a line box to visual order and
+// repositions them from the start of the line. para is the paragraph
+// embedding level.
+//
+// Trailing fragments consisting of word separators only are reset to the
+// paragraph level (rule L1). Then, from the highest level down to the lowest
+// odd level, every run of fragments at that level or higher is reversed
+// (rule L2). Gaps between fragments, as left by inline box edges, stay with
+// the fragment which logically follows them.
+func ReorderLine(line *LineBox, para text.Level, buffers map[layout.NodeID]GlyphBuffer) {
+	frags := line.Frags
+	if len(frags) == 0 {
+		return
+	}
+	for i := len(frags) - 1; i >= 0 && separatorsOnly(frags[i], buffers); i-- {
+		frags[i].Level = para
+	}
+	var highest text.Level
+	lowestOdd := text.Level(255)
+	for _, f := range frags {
+		highest = max(highest, f.Level)
+		if f.Level.IsRTL() {
+			lowestOdd = min(lowestOdd, f.Level)
+		}
+	}
+	if lowestOdd > highest {
+		return // all left-to-right
+	}
+	start := frags[0].Frame.X
+	gaps := make([]float32, len(frags))
+	end := start
+	for i, f := range frags {
+		gaps[i] = f.Frame.X - end
+		end = f.Frame.X + f.Frame.W
+	}
+	type item struct {
+		frag GlyphFragment
+		gap  float32
+	}
+	items := make([]item, len(frags))
+	for i := range frags {
+		items[i] = item{frags[i], gaps[i]}
+	}
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(items); {
+			if items[i].frag.Level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(items) && items[j].frag.Level >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				items[a], items[b] = items[b], items[a]
+			}
+			i = j
+		}
+	}
+	x := start
+	for i, it := range items {
+		f := it.frag
+		if !f.Level.IsRTL() {
+			x += it.gap
+		}
+		f.Frame.X = x
+		x += f.Frame.W
+		if f.Level.IsRTL() {
+			x += it.gap
+		}
+		frags[i] = f
+	}
+}
+
+// separatorsOnly tells whether f holds nothing but word separators.
+func separatorsOnly(f GlyphFragment, buffers map[layout.NodeID]GlyphBuffer) bool {
+	if f.Kind == FragAtomic {
+		return false
+	}
+	glyphs := fragGlyphs(f, buffers)
+	for _, g := range glyphs {
+		if g.Flags&GlyphWordSeparator == 0 {
+			return false
+		}
+	}
+	return len(glyphs) > 0
+}
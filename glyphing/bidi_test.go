@@ -0,0 +1,73 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestReorderLine(t *testing.T) {
+	buffers := map[layout.NodeID]GlyphBuffer{1: {Glyphs: wordGlyphs("abcdefgh ")}}
+	frag := func(id layout.NodeID, x, w float32, level text.Level) GlyphFragment {
+		from := int(x / 10)
+		return GlyphFragment{SourceID: id, Frame: layout.Rect{X: x, W: w}, Level: level,
+			Slice: GlyphSlice{BufferOwner: 1, From: from, To: from + int(w/10)}}
+	}
+	tests := []struct {
+		name  string
+		para  text.Level
+		frags []GlyphFragment
+		order []layout.NodeID // SourceIDs in visual order
+		x     []float32
+	}{
+		{
+			name:  "ltr",
+			frags: []GlyphFragment{frag(1, 0, 20, 0), frag(2, 20, 20, 0)},
+			order: []layout.NodeID{1, 2}, x: []float32{0, 20},
+		},
+		{
+			name:  "rtl_run_in_ltr",
+			frags: []GlyphFragment{frag(1, 0, 20, 0), frag(2, 20, 10, 1), frag(3, 30, 20, 1), frag(4, 50, 10, 0)},
+			order: []layout.NodeID{1, 3, 2, 4}, x: []float32{0, 20, 40, 50},
+		},
+		{
+			name:  "numbers_in_rtl",
+			para:  1,
+			frags: []GlyphFragment{frag(1, 0, 20, 1), frag(2, 20, 10, 2), frag(3, 30, 20, 2), frag(4, 50, 10, 1)},
+			order: []layout.NodeID{4, 2, 3, 1}, x: []float32{0, 10, 20, 40},
+		},
+		{
+			name:  "trailing_space_at_paragraph_level",
+			frags: []GlyphFragment{frag(1, 0, 20, 1), frag(2, 20, 20, 1), frag(3, 80, 10, 1)},
+			order: []layout.NodeID{2, 1, 3}, x: []float32{0, 20, 80},
+		},
+		{
+			name:  "gap_follows_fragment",
+			frags: []GlyphFragment{frag(1, 0, 20, 1), frag(2, 25, 20, 1)},
+			order: []layout.NodeID{2, 1}, x: []float32{0, 25},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := LineBox{Frags: tt.frags}
+			ReorderLine(&line, tt.para, buffers)
+			for i, f := range line.Frags {
+				if f.SourceID != tt.order[i] || f.Frame.X != tt.x[i] {
+					t.Fatalf("fragment %d: expected %d at x=%v, got %d at x=%v", i, tt.order[i], tt.x[i], f.SourceID, f.Frame.X)
+				}
+			}
+		})
+	}
+}
+
+func TestReorderLines_AtomicInline(t *testing.T) {
+	lines := []LineBox{{Frags: []GlyphFragment{
+		{SourceID: 1, Kind: FragAtomic, Frame: layout.Rect{W: 30}, Level: 1},
+		{SourceID: 2, Kind: FragAtomic, Frame: layout.Rect{X: 30, W: 50}, Level: 1},
+	}}}
+	ReorderLines(lines, &layout.ComputedStyle{Direction: layout.DirectionRTL}, nil)
+	if f := lines[0].Frags; f[0].SourceID != 2 || f[0].Frame.X != 0 || f[1].Frame.X != 50 {
+		t.Fatalf("expected atomic inlines mirrored, got %+v", f)
+	}
+}
@@ -1 +1,17 @@
+// Package glyphing holds shaped glyph runs and the line boxes built from them,
+// together with post-breaking steps on line boxes such as text alignment.
+//
+// Shaping is behind the Shaper interface (a TextRef plus font, language,
+// script and direction give a GlyphBuffer). FixedAdvanceShaper gives
+// deterministic monospace buffers for tests and terminal output.
+//
+// FontMatcher selects faces by CSS Fonts §5 matching, Itemize splits a TextRef
+// into runs by script, face coverage and bidi level, and ShapeRuns shapes one
+// GlyphBuffer per run.
+//
+// SFNTShaper shapes simple scripts from the metrics read by package sfnt,
+// without GSUB or GPOS.
+//
+// Shapers flag tab glyphs; where white-space preserves tabs, the line breaker
+// sets their advances with GlyphBuffer.PlaceTabs.
 package glyphing
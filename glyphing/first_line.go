@@ -0,0 +1,92 @@
+package glyphing
+
+import (
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// ::first-line styles (CSS Pseudo-Elements 4 §2.1) apply to whatever content
+// ends up on the first line, which is only known after line breaking. The
+// content is broken with its own styles; the glyph slices of the first line
+// are then shaped again with the first-line font and spacing (see
+// layout.FirstLineStyle). Lines are not broken again: with a wider first-line
+// font the first line may overflow. The inline layouter should use the
+// first-line style for the strut of the first line.
+
+// ReshapeFirstLine re-shapes the glyph slices of the first line of a block
+// container with its ::first-line style. styles holds the computed style of
+// each fragment's SourceID, whose Parent chain leads to the block container's
+// style (see layout.FirstLineStyle). Fragments whose style does not change are
+// left as they are. The re-shaped glyphs replace the slices' glyphs in
+// buffers; slices of the same buffers on all lines are adjusted to the new
+// glyph indices. Fragments without a TextRange are left as they are. Call it
+// before the other post-breaking steps.
+func ReshapeFirstLine(lines []LineBox, ctx layout.InlineContext, shaper Shaper, src text.TextReader, styles map[layout.NodeID]*layout.ComputedStyle, buffers map[layout.NodeID]GlyphBuffer) error {
+	if len(lines) == 0 || ctx.Block == nil || ctx.Block.Style == nil || ctx.Block.Style.FirstLine == nil {
+		return nil
+	}
+	block := ctx.Block.Style
+	frags := lines[0].Frags
+	var dx float32
+	for i := range frags {
+		f := &frags[i]
+		f.Frame.X += dx
+		if f.Kind != FragGlyphSlice || f.Slice.TextRange.End <= f.Slice.TextRange.Start {
+			continue
+		}
+		style := styles[f.SourceID]
+		if style == nil {
+			style = block
+		}
+		fl := layout.FirstLineStyle(block, style)
+		if fl == style {
+			continue
+		}
+		buf, ok := buffers[f.Slice.BufferOwner]
+		if !ok || f.Slice.From < 0 || f.Slice.To > len(buf.Glyphs) || f.Slice.From >= f.Slice.To {
+			continue
+		}
+		params := ShapeParams{Font: StyleFont(fl)}
+		if len(fl.FontFamily) > 0 {
+			params.Font.Family = fl.FontFamily[0]
+		}
+		if f.Level%2 == 1 {
+			params.Direction = text.DirectionRTL
+		}
+		shaped, err := shaper.Shape(src, text.TextRef{Source: buf.Text.Source, Range: f.Slice.TextRange}, params)
+		if err != nil {
+			return err
+		}
+		letter, word := Spacing(fl)
+		ApplySpacing(&shaped, letter, word)
+
+		from, to := f.Slice.From, f.Slice.To
+		glyphs := make([]Glyph, 0, len(buf.Glyphs)-(to-from)+len(shaped.Glyphs))
+		glyphs = append(append(append(glyphs, buf.Glyphs[:from]...), shaped.Glyphs...), buf.Glyphs[to:]...)
+		buf.Glyphs = glyphs
+		buffers[f.Slice.BufferOwner] = buf
+		if delta := len(shaped.Glyphs) - (to - from); delta != 0 {
+			shiftSlices(lines, f.Slice.BufferOwner, to, delta)
+		}
+		f.Slice.To = from + len(shaped.Glyphs)
+
+		w := shaped.SliceWidth(0, len(shaped.Glyphs), i == len(frags)-1)
+		dx += w - f.Frame.W
+		f.Frame.W = w
+	}
+	return nil
+}
+
+// shiftSlices moves the glyph slices of owner's buffer starting at or after
+// glyph index at by delta.
+func shiftSlices(lines []LineBox, owner layout.NodeID, at, delta int) {
+	for i := range lines {
+		for j := range lines[i].Frags {
+			s := &lines[i].Frags[j].Slice
+			if lines[i].Frags[j].Kind == FragGlyphSlice && s.BufferOwner == owner && s.From >= at {
+				s.From += delta
+				s.To += delta
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestReshapeFirstLine(t *testing.T) {
+	src := text.NewBuffer(1)
+	ref := text.TextRef{Source: 1, Range: src.Append("ab cd")}
+	em := text.TextRef{Source: 1, Range: src.Append("x")}
+	// Glyph 0 of text 7 is a ligature of "ab".
+	buffers := map[layout.NodeID]GlyphBuffer{
+		7: {Text: ref, Glyphs: []Glyph{
+			{ID: 1, Advance: 10, Cluster: 0},
+			{ID: ' ', Advance: 10, Cluster: 2, Flags: GlyphWordSeparator},
+			{ID: 'c', Advance: 10, Cluster: 3},
+			{ID: 'd', Advance: 10, Cluster: 4},
+		}},
+		8: {Text: em, Glyphs: []Glyph{{ID: 'x', Advance: 12, Cluster: 5}}},
+	}
+	lines := []LineBox{
+		{Frame: layout.Rect{W: 100}, Frags: []GlyphFragment{
+			{SourceID: 7, Frame: layout.Rect{W: 20}, Slice: GlyphSlice{BufferOwner: 7, From: 0, To: 2, TextRange: text.TextRange{Start: 0, End: 3}}},
+			{SourceID: 8, Frame: layout.Rect{X: 20, W: 12}, Slice: GlyphSlice{BufferOwner: 8, From: 0, To: 1, TextRange: em.Range}},
+		}},
+		{Frame: layout.Rect{W: 100}, Frags: []GlyphFragment{
+			{SourceID: 7, Frame: layout.Rect{W: 20}, Slice: GlyphSlice{BufferOwner: 7, From: 2, To: 4, TextRange: text.TextRange{Start: 3, End: 5}}},
+		}},
+	}
+	block := &layout.ComputedStyle{FontSizePx: 10, FontFamily: []string{"mono"}}
+	block.FirstLine = &layout.ComputedStyle{FontSizePx: 20, FontFamily: []string{"mono"}, FontWeight: 700}
+	styles := map[layout.NodeID]*layout.ComputedStyle{
+		7: block,
+		8: {FontSizePx: 12, FontFamily: []string{"mono"}, Parent: block, Specified: layout.PropFontSize}, // own font size
+	}
+	shaper := FixedAdvanceShaper{Metrics: map[string]FixedMetrics{"mono": {Advance: 1, Ascent: 0.8, Descent: 0.2}}}
+	ctx := layout.InlineContext{Block: &layout.LayoutNode{NodeID: 3, Style: block}}
+
+	if err := ReshapeFirstLine(lines, ctx, shaper, src, styles, buffers); err != nil {
+		t.Fatalf("ReshapeFirstLine error: %v", err)
+	}
+	first := lines[0].Frags
+	if s := first[0].Slice; s.From != 0 || s.To != 3 || first[0].Frame.W != 60 {
+		t.Errorf("expected the first fragment to hold 3 glyphs at 20px, got %+v", first[0])
+	}
+	if first[1].Frame.X != 60 || first[1].Frame.W != 12 {
+		t.Errorf("expected the second fragment to move to 60 and keep its size, got %+v", first[1].Frame)
+	}
+	if s := lines[1].Frags[0].Slice; s.From != 3 || s.To != 5 || lines[1].Frags[0].Frame.W != 20 {
+		t.Errorf("expected the second line's slice to follow the new glyphs, got %+v", lines[1].Frags[0])
+	}
+	glyphs := buffers[7].Glyphs
+	if len(glyphs) != 5 || glyphs[1].Cluster != 1 || glyphs[2].Advance != 20 || glyphs[3].Advance != 10 {
+		t.Errorf("unexpected glyphs after re-shaping: %+v", glyphs)
+	}
+
+	plain := &layout.ComputedStyle{FontSizePx: 10}
+	before := buffers[7].Glyphs
+	ctx.Block.Style = plain
+	if err := ReshapeFirstLine(lines, ctx, shaper, src, styles, buffers); err != nil || len(buffers[7].Glyphs) != len(before) {
+		t.Fatalf("expected no change without a ::first-line style")
+	}
+}
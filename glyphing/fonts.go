@@ -0,0 +1,223 @@
+package glyphing
+
+import (
+	"strings"
+
+	"github.com/npillmayer/css-box-layout/layout"
+)
+
+// Font matching (CSS Fonts 4 §5.2). Within a family, faces are narrowed down
+// by font-stretch, then font-style, then font-weight; the first remaining face
+// is used. Characters a matched face does not cover fall back to the next
+// family of font-family, then to a fallback face (see Itemize).
+
+// FontFace describes a face available for shaping. Weight and stretch are
+// ranges, to support variable fonts; other faces have equal bounds.
+type FontFace struct {
+	Family                 string
+	Name                   string // identifies the face to a Shaper, e.g. a file path
+	WeightMin, WeightMax   uint16
+	Style                  layout.FontStyle
+	StretchMin, StretchMax float32      // percentages of the normal width
+	Coverage               RuneCoverage // characters with glyphs; nil for all
+}
+
+// HasRune tells whether f has a glyph for r.
+func (f *FontFace) HasRune(r rune) bool {
+	return f.Coverage == nil || f.Coverage.HasRune(r)
+}
+
+// RuneCoverage is the character set of a face.
+type RuneCoverage interface {
+	HasRune(r rune) bool
+}
+
+// RuneRanges is a RuneCoverage of inclusive ranges.
+type RuneRanges [][2]rune
+
+func (rr RuneRanges) HasRune(r rune) bool {
+	for _, rg := range rr {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// FontMatcher selects faces for font-family entries and for characters no
+// listed family covers.
+type FontMatcher interface {
+	// MatchFamily returns the face of family which best matches d, or false
+	// if the family is not available.
+	MatchFamily(family string, d FontDescriptor) (*FontFace, bool)
+	// Fallback returns a face covering r which best matches d, or false.
+	Fallback(r rune, d FontDescriptor) (*FontFace, bool)
+}
+
+// FontCollection is a FontMatcher over a fixed set of faces. Family names
+// compare case-insensitively; Generic maps generic families (serif,
+// monospace, …) to family names. Fallback tries the families in the order of
+// their first face.
+type FontCollection struct {
+	Faces   []FontFace
+	Generic map[string][]string
+}
+
+var _ FontMatcher = (*FontCollection)(nil)
+
+func (c *FontCollection) MatchFamily(family string, d FontDescriptor) (*FontFace, bool) {
+	if names, ok := c.Generic[strings.ToLower(family)]; ok {
+		for _, name := range names {
+			if f, ok := c.matchFaces(name, d); ok {
+				return f, true
+			}
+		}
+		return nil, false
+	}
+	return c.matchFaces(family, d)
+}
+
+func (c *FontCollection) Fallback(r rune, d FontDescriptor) (*FontFace, bool) {
+	seen := make(map[string]bool)
+	for i := range c.Faces {
+		family := strings.ToLower(c.Faces[i].Family)
+		if seen[family] {
+			continue
+		}
+		seen[family] = true
+		if f, ok := c.matchFaces(family, d); ok && f.HasRune(r) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func (c *FontCollection) matchFaces(family string, d FontDescriptor) (*FontFace, bool) {
+	var faces []*FontFace
+	for i := range c.Faces {
+		if strings.EqualFold(c.Faces[i].Family, family) {
+			faces = append(faces, &c.Faces[i])
+		}
+	}
+	if len(faces) == 0 {
+		return nil, false
+	}
+	return matchFace(faces, d), true
+}
+
+// matchFace implements step 4 of the font matching algorithm.
+func matchFace(faces []*FontFace, d FontDescriptor) *FontFace {
+	stretch := d.Stretch
+	if stretch == 0 {
+		stretch = 100
+	}
+	faces = narrow(faces, func(f *FontFace) matchScore {
+		return stretchScore(f.StretchMin, f.StretchMax, stretch)
+	})
+	faces = narrow(faces, func(f *FontFace) matchScore {
+		return matchScore{tier: styleRank(d.Style, f.Style)}
+	})
+	weight := float32(d.Weight)
+	if weight == 0 {
+		weight = 400
+	}
+	faces = narrow(faces, func(f *FontFace) matchScore {
+		return weightScore(float32(f.WeightMin), float32(f.WeightMax), weight)
+	})
+	return faces[0]
+}
+
+// matchScore orders candidates: lower tiers first, then smaller distances.
+type matchScore struct {
+	tier int
+	dist float32
+}
+
+func (s matchScore) less(t matchScore) bool {
+	return s.tier < t.tier || s.tier == t.tier && s.dist < t.dist
+}
+
+// narrow keeps the faces with the best score, in their original order.
+func narrow(faces []*FontFace, score func(*FontFace) matchScore) []*FontFace {
+	best := score(faces[0])
+	for _, f := range faces[1:] {
+		if s := score(f); s.less(best) {
+			best = s
+		}
+	}
+	var kept []*FontFace
+	for _, f := range faces {
+		if score(f) == best {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// rangeScore scores the range [lo, hi] for a desired value: values in range
+// match exactly, then the nearest values in the preferred direction, then
+// those in the other direction.
+func rangeScore(lo, hi, desired float32, preferBelow bool) matchScore {
+	switch {
+	case lo <= desired && desired <= hi:
+		return matchScore{}
+	case hi < desired:
+		if preferBelow {
+			return matchScore{tier: 1, dist: desired - hi}
+		}
+		return matchScore{tier: 2, dist: desired - hi}
+	default:
+		if preferBelow {
+			return matchScore{tier: 2, dist: lo - desired}
+		}
+		return matchScore{tier: 1, dist: lo - desired}
+	}
+}
+
+// stretchScore: narrower widths are preferred for desired widths up to 100%,
+// wider ones above.
+func stretchScore(lo, hi, desired float32) matchScore {
+	if lo == 0 && hi == 0 {
+		lo, hi = 100, 100
+	}
+	return rangeScore(lo, hi, desired, desired <= 100)
+}
+
+// weightScore: for desired weights from 400 to 500, heavier weights up to 500
+// are tried first, then lighter ones, then heavier ones above 500. Below 400,
+// lighter weights are preferred, above 500 heavier ones.
+func weightScore(lo, hi, desired float32) matchScore {
+	if lo == 0 && hi == 0 {
+		lo, hi = 400, 400
+	}
+	if desired < 400 || desired > 500 || (lo <= desired && desired <= hi) {
+		return rangeScore(lo, hi, desired, desired < 400)
+	}
+	switch {
+	case lo > desired && lo <= 500:
+		return matchScore{tier: 1, dist: lo - desired}
+	case hi < desired:
+		return matchScore{tier: 2, dist: desired - hi}
+	default:
+		return matchScore{tier: 3, dist: lo - desired}
+	}
+}
+
+// styleRank orders face styles for a desired font-style.
+func styleRank(desired, face layout.FontStyle) int {
+	var order [3]layout.FontStyle
+	switch desired {
+	case layout.FontStyleItalic:
+		order = [3]layout.FontStyle{layout.FontStyleItalic, layout.FontStyleOblique, layout.FontStyleNormal}
+	case layout.FontStyleOblique:
+		order = [3]layout.FontStyle{layout.FontStyleOblique, layout.FontStyleItalic, layout.FontStyleNormal}
+	default:
+		order = [3]layout.FontStyle{layout.FontStyleNormal, layout.FontStyleOblique, layout.FontStyleItalic}
+	}
+	for i, s := range order {
+		if s == face {
+			return i
+		}
+	}
+	return len(order)
+}
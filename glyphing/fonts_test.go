@@ -0,0 +1,125 @@
+package glyphing
+
+import (
+	"testing"
+	"unicode"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestFontCollection_MatchFamily(t *testing.T) {
+	fonts := &FontCollection{
+		Faces: []FontFace{
+			{Family: "Sans", Name: "regular", WeightMin: 400, WeightMax: 400},
+			{Family: "Sans", Name: "light", WeightMin: 300, WeightMax: 300},
+			{Family: "Sans", Name: "bold", WeightMin: 700, WeightMax: 700},
+			{Family: "Sans", Name: "italic", WeightMin: 400, WeightMax: 400, Style: layout.FontStyleItalic},
+			{Family: "Sans", Name: "condensed", WeightMin: 400, WeightMax: 400, StretchMin: 75, StretchMax: 75},
+			{Family: "Var", Name: "variable", WeightMin: 100, WeightMax: 900},
+		},
+		Generic: map[string][]string{"sans-serif": {"Missing", "sans"}},
+	}
+	tests := []struct {
+		name   string
+		family string
+		d      FontDescriptor
+		want   string
+	}{
+		{name: "normal", family: "Sans", want: "regular"},
+		{name: "case_insensitive", family: "SANS", d: FontDescriptor{Weight: 400}, want: "regular"},
+		{name: "weight_450_tries_lighter", family: "Sans", d: FontDescriptor{Weight: 450}, want: "regular"},
+		{name: "weight_600_tries_heavier", family: "Sans", d: FontDescriptor{Weight: 600}, want: "bold"},
+		{name: "weight_350_tries_lighter", family: "Sans", d: FontDescriptor{Weight: 350}, want: "light"},
+		{name: "weight_1000", family: "Sans", d: FontDescriptor{Weight: 1000}, want: "bold"},
+		{name: "italic", family: "Sans", d: FontDescriptor{Style: layout.FontStyleItalic}, want: "italic"},
+		{name: "oblique_uses_italic", family: "Sans", d: FontDescriptor{Style: layout.FontStyleOblique, Weight: 700}, want: "italic"},
+		{name: "narrower_first", family: "Sans", d: FontDescriptor{Stretch: 87.5}, want: "condensed"},
+		{name: "wider_first", family: "Sans", d: FontDescriptor{Stretch: 112.5}, want: "regular"},
+		{name: "variable_range", family: "Var", d: FontDescriptor{Weight: 650}, want: "variable"},
+		{name: "generic", family: "sans-serif", d: FontDescriptor{Weight: 700}, want: "bold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := fonts.MatchFamily(tt.family, tt.d)
+			if !ok || f.Name != tt.want {
+				t.Fatalf("expected face %q, got %+v (ok=%v)", tt.want, f, ok)
+			}
+		})
+	}
+	if _, ok := fonts.MatchFamily("Serif", FontDescriptor{}); ok {
+		t.Fatalf("expected no match for a missing family")
+	}
+}
+
+func TestItemize(t *testing.T) {
+	fonts := &FontCollection{Faces: []FontFace{
+		{Family: "Latin", Coverage: RuneRanges{{0x20, 0x7E}}},
+		{Family: "Hebrew", Coverage: RuneRanges{{0x590, 0x5FF}}},
+		{Family: "CJK", Coverage: RuneRanges{{0x4E00, 0x9FFF}}},
+		{Family: "Emoji", Coverage: RuneRanges{{0x1F600, 0x1F64F}}},
+	}}
+	src := text.NewBuffer(1)
+	s := "ab אב 日😀"
+	ref := text.TextRef{Source: 1, Range: src.Append(s)}
+	style := &layout.ComputedStyle{FontFamily: []string{"Missing", "Latin"}, FontSizePx: 10}
+
+	runs := Itemize(src, ref, text.BidiLevels(s, text.DirectionLTR), style, fonts)
+	want := []struct {
+		text   string
+		family string
+		script string
+		level  text.Level
+	}{
+		{"ab ", "Latin", "Latin", 0},
+		{"אב", "Hebrew", "Hebrew", 1},
+		{" ", "Latin", "Hebrew", 0},
+		{"日", "CJK", "Han", 0},
+		{"😀", "Emoji", "Han", 0},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("expected %d runs, got %+v", len(want), runs)
+	}
+	for i, w := range want {
+		run := runs[i]
+		if got := src.String(run.Text.Range); got != w.text || run.Face == nil || run.Face.Family != w.family ||
+			run.Script != w.script || run.Level != w.level {
+			t.Errorf("run %d: expected %q %s/%s/%d, got %q %+v", i, w.text, w.family, w.script, w.level, got, run)
+		}
+	}
+
+	buffers, err := ShapeRuns(FixedAdvanceShaper{}, src, runs, style, "ja")
+	if err != nil {
+		t.Fatalf("ShapeRuns error: %v", err)
+	}
+	if len(buffers) != len(runs) || buffers[1].Text != runs[1].Text || len(buffers[1].Glyphs) != 2 {
+		t.Fatalf("expected one buffer per run, got %+v", buffers)
+	}
+}
+
+func TestScriptOf(t *testing.T) {
+	for _, tt := range []struct {
+		r    rune
+		want string
+	}{
+		{'a', "Latin"}, {'1', "Common"}, {'é', "Latin"}, {'\u0301', "Inherited"}, {'α', "Greek"},
+		{'ب', "Arabic"}, {'あ', "Hiragana"}, {'漢', "Han"}, {'\U0001F600', "Common"}, {'\u0378', "Unknown"},
+	} {
+		if got := scriptOf(tt.r); got != tt.want {
+			t.Errorf("%U: expected script %s, got %s", tt.r, tt.want, got)
+		}
+	}
+	// The sorted table agrees with unicode.Scripts.
+	for r := rune(0x80); r <= unicode.MaxRune; r += 97 {
+		want := "Unknown"
+		for name, table := range unicode.Scripts {
+			if unicode.Is(table, r) {
+				want = name
+				break
+			}
+		}
+		if got := scriptOf(r); got != want {
+			t.Fatalf("%U: expected script %s, got %s", r, want, got)
+		}
+	}
+}
@@ -16,19 +16,33 @@ type Glyph struct {
 	// Optional but strongly recommended even now:
 	// maps glyph back to a byte offset (or cluster start) in the shaped text range.
 	Cluster text.TextPos
+
+	Flags GlyphFlags
 }
 
+type GlyphFlags uint8
+
+const (
+	GlyphWordSeparator GlyphFlags = 1 << iota // space-like glyph between words (justification, word-spacing)
+	GlyphTab                                  // preserved tab; its advance depends on its position, see PlaceTabs
+)
+
 type GlyphBuffer struct {
 	// The source text slice this buffer was shaped from.
 	Text text.TextRef
 
-	// Glyph sequence in logical order for LTR.
+	// Glyph sequence in logical order, also for right-to-left text; renderers
+	// draw the glyphs of odd-level fragments from right to left.
 	Glyphs []Glyph
 
 	// Metrics needed for line layout:
 	Ascent  float32
 	Descent float32
 	// Optionally: LineGap, etc.
+
+	// Spacing already included in the glyph advances (see ApplySpacing).
+	LetterSpacing float32
+	WordSpacing   float32
 }
 
 type GlyphSlice struct {
@@ -49,4 +63,5 @@ type SyntheticReason uint8
 const (
 	SynthHyphen SyntheticReason = iota
 	SynthCollapsedWhitespace
+	SynthEllipsis // text-overflow or line-clamp, see TruncateLines
 )
@@ -0,0 +1,28 @@
+package glyphing
+
+import "github.com/npillmayer/css-box-layout/layout"
+
+// IndentLines applies text-indent to the line boxes of a block container
+// after line breaking: an indented line starts later and has less width
+// available. Line breaking must already have used the reduced widths, but
+// left the line boxes and their fragments at the start of the line (see
+// layout.InlineContext.LineIndent). Call it before AlignLines.
+//
+// In right-to-left blocks (ctx.Direction) the indentation is at the right:
+// fragments stay in place and AlignLines aligns them against the reduced line.
+func IndentLines(lines []LineBox, ctx layout.InlineContext) {
+	rtl := ctx.Direction == layout.DirectionRTL
+	for i := range lines {
+		indent := ctx.LineIndent(i == 0, i > 0 && lines[i-1].ForcedBreak)
+		if indent == 0 {
+			continue
+		}
+		line := &lines[i]
+		line.Frame.W -= indent
+		if rtl {
+			continue
+		}
+		line.Frame.X += indent
+		shiftFrags(line.Frags, indent)
+	}
+}
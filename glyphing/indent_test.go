@@ -0,0 +1,68 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/layout"
+)
+
+func TestIndentLines(t *testing.T) {
+	block := &layout.LayoutNode{Style: &layout.ComputedStyle{
+		TextIndent: layout.TextIndent{Length: layout.Length{Kind: layout.LenPx, Value: 20}, EachLine: true},
+	}}
+	ctx := layout.InlineContext{Block: block, TextIndent: 20}
+	line := func(forced bool) LineBox {
+		return LineBox{
+			Frame:       layout.Rect{W: 100},
+			Frags:       []GlyphFragment{{Frame: layout.Rect{W: 30}}},
+			ForcedBreak: forced,
+		}
+	}
+	lines := []LineBox{line(true), line(false), line(false)}
+	IndentLines(lines, ctx)
+	for i, want := range []float32{20, 20, 0} {
+		if lines[i].Frame.X != want || lines[i].Frame.W != 100-want || lines[i].Frags[0].Frame.X != want {
+			t.Errorf("line %d: expected indent %v, got frame %+v, fragment x=%v", i, want, lines[i].Frame, lines[i].Frags[0].Frame.X)
+		}
+	}
+}
+
+// captureLayouter records the inline context of each block container and
+// reports one empty line for it.
+type captureLayouter map[layout.BoxID]layout.InlineContext
+
+func (c captureLayouter) LayoutInline(root *layout.LayoutNode, maxWidth float32, _ layout.AtomicSizer, ctx layout.InlineContext) ([]layout.LineBox, error) {
+	c[ctx.Block.BoxID] = ctx
+	return []layout.LineBox{{Frame: layout.Rect{W: maxWidth, H: 10}}}, nil
+}
+
+func TestIndentLines_AnonymousBlockRTL(t *testing.T) {
+	// <div dir=rtl style="text-indent:20px">text<p>…</p></div>
+	anon := &layout.LayoutNode{BoxID: 2, Box: layout.BoxAnonymousBlock, Children: []*layout.LayoutNode{
+		{BoxID: 3, Box: layout.BoxAnonymousInline},
+	}}
+	p := &layout.LayoutNode{BoxID: 4, NodeID: 2, Box: layout.BoxBlock, Style: &layout.ComputedStyle{Width: layout.Length{Kind: layout.LenAuto}}}
+	root := &layout.LayoutNode{BoxID: 1, NodeID: 1, Box: layout.BoxBlock, Children: []*layout.LayoutNode{anon, p},
+		Style: &layout.ComputedStyle{
+			Width:      layout.Length{Kind: layout.LenAuto},
+			Direction:  layout.DirectionRTL,
+			TextIndent: layout.TextIndent{Length: layout.Length{Kind: layout.LenPx, Value: 20}},
+		}}
+	used, err := layout.ResolveUsedValues(root, layout.ResolveContext{ContainingBlock: layout.Rect{W: 100}})
+	if err != nil {
+		t.Fatalf("ResolveUsedValues error: %v", err)
+	}
+	contexts := captureLayouter{}
+	if _, err := layout.FlowLayout(root, used, contexts, nil, layout.LayoutContext{}, layout.LayoutOptions{}); err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	ctx, ok := contexts[anon.BoxID]
+	if !ok {
+		t.Fatalf("expected the anonymous block to be laid out")
+	}
+	lines := []LineBox{{Frame: layout.Rect{W: 100}, Frags: []GlyphFragment{{Frame: layout.Rect{W: 30}}}}}
+	IndentLines(lines, ctx)
+	if lines[0].Frame.X != 0 || lines[0].Frame.W != 80 || lines[0].Frags[0].Frame.X != 0 {
+		t.Fatalf("expected the indent at the right of the line, got frame %+v, fragment x=%v", lines[0].Frame, lines[0].Frags[0].Frame.X)
+	}
+}
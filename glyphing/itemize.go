@@ -0,0 +1,221 @@
+package glyphing
+
+import (
+	"sort"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// Itemization splits the text of a box into runs which can each be shaped
+// with a single face, script and direction.
+//
+// Characters of the Common and Inherited scripts (spaces, punctuation,
+// combining marks, emoji) take the script of the preceding character, or of
+// the first following one at the start of the text (UAX #24). They also stay
+// in the preceding character's face if it covers them, so runs do not split
+// at every space.
+
+// TextRun is a maximal range of text with one face, script and bidi level.
+type TextRun struct {
+	Text   text.TextRef
+	Face   *FontFace // nil if no face is available
+	Script string    // Unicode script name as in unicode.Scripts
+	Level  text.Level
+}
+
+// StyleFont returns the font descriptor of style, without a family.
+func StyleFont(style *layout.ComputedStyle) FontDescriptor {
+	if style == nil {
+		return FontDescriptor{}
+	}
+	return FontDescriptor{
+		Weight:  style.FontWeight,
+		Style:   style.FontStyle,
+		Stretch: style.FontStretch,
+		SizePx:  style.FontSizePx,
+	}
+}
+
+// Itemize splits ref into runs by script, font coverage and bidi level.
+// levels holds the bidi level of each byte of ref, as returned by
+// text.BidiLevels; nil means level 0 throughout. Faces are selected from the
+// font-family list of style through fonts.
+func Itemize(src text.TextReader, ref text.TextRef, levels []text.Level, style *layout.ComputedStyle, fonts FontMatcher) []TextRun {
+	s := src.String(ref.Range)
+	if s == "" {
+		return nil
+	}
+	var families []string
+	if style != nil {
+		families = style.FontFamily
+	}
+	d := StyleFont(style)
+	scripts := resolveScripts(s)
+
+	var runs []TextRun
+	var face *FontFace
+	i := 0
+	for pos, r := range s {
+		var level text.Level
+		if pos < len(levels) {
+			level = levels[pos]
+		}
+		face = selectFace(r, face, families, d, fonts)
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		start := ref.Range.Start + text.TextPos(pos)
+		end := start + text.TextPos(size)
+		if n := len(runs); n > 0 {
+			last := &runs[n-1]
+			if last.Face == face && last.Script == scripts[i] && last.Level == level {
+				last.Text.Range.End = end
+				i++
+				continue
+			}
+		}
+		runs = append(runs, TextRun{
+			Text:   text.TextRef{Source: ref.Source, Range: text.TextRange{Start: start, End: end}},
+			Face:   face,
+			Script: scripts[i],
+			Level:  level,
+		})
+		i++
+	}
+	return runs
+}
+
+// selectFace finds the face for r: the previous face for characters without
+// a script of their own, else the first family covering r, else a fallback
+// face, else the first available family.
+func selectFace(r rune, prev *FontFace, families []string, d FontDescriptor, fonts FontMatcher) *FontFace {
+	if prev != nil && isCommonScript(r) && prev.HasRune(r) {
+		return prev
+	}
+	if fonts == nil {
+		return nil
+	}
+	var first *FontFace
+	for _, family := range families {
+		f, ok := fonts.MatchFamily(family, d)
+		if !ok {
+			continue
+		}
+		if f.HasRune(r) {
+			return f
+		}
+		if first == nil {
+			first = f
+		}
+	}
+	if f, ok := fonts.Fallback(r, d); ok {
+		return f
+	}
+	return first
+}
+
+// resolveScripts returns the resolved script of each rune of s.
+func resolveScripts(s string) []string {
+	var scripts []string
+	for _, r := range s {
+		scripts = append(scripts, scriptOf(r))
+	}
+	next := "Common"
+	for i := len(scripts) - 1; i >= 0; i-- {
+		if scripts[i] != "Common" && scripts[i] != "Inherited" {
+			next = scripts[i]
+		}
+	}
+	prev := next
+	for i, sc := range scripts {
+		if sc == "Common" || sc == "Inherited" {
+			scripts[i] = prev
+		} else {
+			prev = sc
+		}
+	}
+	return scripts
+}
+
+func isCommonScript(r rune) bool {
+	return unicode.Is(unicode.Common, r) || unicode.Is(unicode.Inherited, r)
+}
+
+// scriptOf returns the Unicode script of r.
+func scriptOf(r rune) string {
+	if r < 0x80 {
+		if unicode.IsLetter(r) {
+			return "Latin"
+		}
+		return "Common"
+	}
+	table := scriptTable()
+	i := sort.Search(len(table), func(i int) bool { return table[i].hi >= r })
+	if i < len(table) && table[i].lo <= r {
+		return table[i].name
+	}
+	return "Unknown"
+}
+
+// scriptRange is a range of code points of one script.
+type scriptRange struct {
+	lo, hi rune
+	name   string
+}
+
+// scriptTable holds the ranges of unicode.Scripts sorted by code point, built
+// on first use.
+var scriptTable = sync.OnceValue(func() []scriptRange {
+	var table []scriptRange
+	add := func(lo, hi, stride rune, name string) {
+		if stride == 1 {
+			table = append(table, scriptRange{lo, hi, name})
+			return
+		}
+		for r := lo; r <= hi; r += stride {
+			table = append(table, scriptRange{r, r, name})
+		}
+	}
+	for name, t := range unicode.Scripts {
+		for _, r := range t.R16 {
+			add(rune(r.Lo), rune(r.Hi), rune(r.Stride), name)
+		}
+		for _, r := range t.R32 {
+			add(rune(r.Lo), rune(r.Hi), rune(r.Stride), name)
+		}
+	}
+	sort.Slice(table, func(i, j int) bool { return table[i].lo < table[j].lo })
+	merged := table[:0]
+	for _, r := range table {
+		if n := len(merged); n > 0 && merged[n-1].hi+1 == r.lo && merged[n-1].name == r.name {
+			merged[n-1].hi = r.hi
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+})
+
+// ShapeRuns shapes each run into its own GlyphBuffer, in the font of the run's
+// face and the direction of its level. lang is the content language.
+func ShapeRuns(shaper Shaper, src text.TextReader, runs []TextRun, style *layout.ComputedStyle, lang string) ([]GlyphBuffer, error) {
+	d := StyleFont(style)
+	buffers := make([]GlyphBuffer, 0, len(runs))
+	for _, run := range runs {
+		params := ShapeParams{Font: d, Language: lang, Script: run.Script, Direction: text.DirectionLTR}
+		if run.Face != nil {
+			params.Face, params.Font.Family = run.Face, run.Face.Family
+		}
+		if run.Level.IsRTL() {
+			params.Direction = text.DirectionRTL
+		}
+		buf, err := shaper.Shape(src, run.Text, params)
+		if err != nil {
+			return nil, err
+		}
+		buffers = append(buffers, buf)
+	}
+	return buffers, nil
+}
@@ -1,6 +1,9 @@
 package glyphing
 
-import "github.com/npillmayer/css-box-layout/layout"
+import (
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
 
 type BrokenLine struct {
 	Baseline float32
@@ -29,9 +32,10 @@ const (
 )
 
 type LineBox struct {
-	Frame    layout.Rect
-	Baseline float32
-	Frags    []GlyphFragment
+	Frame       layout.Rect // Frame.W is the width available to the line's content
+	Baseline    float32
+	Frags       []GlyphFragment
+	ForcedBreak bool // line ends in a forced line break (or ends the block)
 }
 
 type GlyphFragment struct {
@@ -42,6 +46,17 @@ type GlyphFragment struct {
 	Kind  FragKind
 	Slice GlyphSlice
 	Synth SyntheticGlyphs
+
+	// Level is the bidi embedding level of the fragment's content; see
+	// ReorderLine.
+	Level text.Level
+
+	// Justification: JustifySpace is added after each of the first
+	// JustifyCount justification opportunities of the fragment, i.e. after
+	// word separators, or after clusters if JustifyClusters is set.
+	JustifySpace    float32
+	JustifyCount    int
+	JustifyClusters bool
 }
 
 type FragKind uint8
@@ -49,6 +64,7 @@ type FragKind uint8
 const (
 	FragGlyphSlice FragKind = iota
 	FragGlyphSynthetic
+	FragAtomic // atomic inline; Frame is its margin box and SourceID its node
 )
 
 type LayoutResult struct {
@@ -0,0 +1,66 @@
+package glyphing
+
+import (
+	"fmt"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/sfnt"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// SFNTShaper is a Shaper for simple scripts using the metrics of OpenType and
+// TrueType fonts: each rune maps to one glyph through the font's cmap (glyph 0
+// if missing), advances come from hmtx and kern-table pairs adjust the advance
+// of the first glyph of a pair. There is no glyph substitution or mark
+// positioning, so complex scripts are not shaped correctly.
+type SFNTShaper struct {
+	Fonts    map[string]*sfnt.Font // by font family
+	Fallback *sfnt.Font            // for families missing from Fonts; may be nil
+}
+
+var _ Shaper = SFNTShaper{}
+
+func (s SFNTShaper) Shape(src text.TextReader, ref text.TextRef, params ShapeParams) (GlyphBuffer, error) {
+	if src == nil || src.ID() != ref.Source {
+		return GlyphBuffer{}, fmt.Errorf("glyphing: text source %d not available for shaping", ref.Source)
+	}
+	font, ok := s.Fonts[params.Font.Family]
+	if !ok {
+		font = s.Fallback
+	}
+	if font == nil {
+		return GlyphBuffer{}, fmt.Errorf("glyphing: no font for family %q", params.Font.Family)
+	}
+	m := SFNTMetrics(font, params.Font.SizePx)
+	scale := params.Font.SizePx / float32(font.UnitsPerEm)
+	buf := GlyphBuffer{Text: ref, Ascent: m.Ascent, Descent: m.Descent}
+	cluster := ref.Range.Start
+	var prev uint16
+	for i, r := range src.String(ref.Range) {
+		pos := ref.Range.Start + text.TextPos(i)
+		if cellWidth(r) > 0 || len(buf.Glyphs) == 0 {
+			cluster = pos
+		}
+		gid, _ := font.GlyphIndex(r)
+		if n := len(buf.Glyphs); n > 0 {
+			buf.Glyphs[n-1].Advance += float32(font.Kern(prev, gid)) * scale
+		}
+		g := Glyph{ID: GlyphID(gid), Advance: float32(font.Advance(gid)) * scale, Cluster: cluster, Flags: glyphFlags(r)}
+		buf.Glyphs = append(buf.Glyphs, g)
+		prev = gid
+	}
+	return buf, nil
+}
+
+// SFNTMetrics returns the metrics of font at the given size, for line height
+// calculation.
+func SFNTMetrics(font *sfnt.Font, sizePx float32) layout.FontMetrics {
+	scale := sizePx / float32(font.UnitsPerEm)
+	return layout.FontMetrics{
+		Ascent:    float32(font.Ascent) * scale,
+		Descent:   float32(font.Descent) * scale,
+		LineGap:   float32(font.LineGap) * scale,
+		XHeight:   float32(font.XHeight) * scale,
+		CapHeight: float32(font.CapHeight) * scale,
+	}
+}
@@ -0,0 +1,49 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/sfnt"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestSFNTShaper(t *testing.T) {
+	// metrics.ttf: 1000 units per em, ascent 800, descent 200; A, B, C and x
+	// map to glyphs 1, 2, 3, 3 with advances 600, 700, 700, 700, glyph 0 has
+	// 500, and the pair A B kerns by -80.
+	font, err := sfnt.Open("testdata/metrics.ttf")
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	src := text.NewBuffer(1)
+	ref := text.TextRef{Source: 1, Range: src.Append("AB x")}
+	shaper := SFNTShaper{Fonts: map[string]*sfnt.Font{"Test": font}}
+
+	buf, err := shaper.Shape(src, ref, ShapeParams{Font: FontDescriptor{Family: "Test", SizePx: 10}})
+	if err != nil {
+		t.Fatalf("Shape error: %v", err)
+	}
+	if buf.Ascent != 8 || buf.Descent != 2 {
+		t.Fatalf("expected ascent/descent 8/2, got %v/%v", buf.Ascent, buf.Descent)
+	}
+	want := []Glyph{
+		{ID: 1, Advance: 5.2, Cluster: 0},
+		{ID: 2, Advance: 7, Cluster: 1},
+		{ID: 0, Advance: 5, Cluster: 2, Flags: GlyphWordSeparator},
+		{ID: 3, Advance: 7, Cluster: 3},
+	}
+	if len(buf.Glyphs) != len(want) {
+		t.Fatalf("expected %d glyphs, got %+v", len(want), buf.Glyphs)
+	}
+	for i, g := range want {
+		if got := buf.Glyphs[i]; got != g {
+			t.Errorf("glyph %d: expected %+v, got %+v", i, g, got)
+		}
+	}
+	if _, err := shaper.Shape(src, ref, ShapeParams{Font: FontDescriptor{Family: "Other"}}); err == nil {
+		t.Fatalf("expected error without a fallback font")
+	}
+	if m := SFNTMetrics(font, 20); m.Ascent != 16 || m.XHeight != 10 || m.CapHeight != 14 {
+		t.Fatalf("unexpected metrics at 20px: %+v", m)
+	}
+}
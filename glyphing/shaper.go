@@ -0,0 +1,150 @@
+package glyphing
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// Shaper turns a range of text into a GlyphBuffer. Implementations wrap a
+// font stack (e.g. HarfBuzz); FixedAdvanceShaper is a deterministic stand-in
+// for tests and terminal rendering.
+//
+// The returned buffer references ref, holds glyphs in logical order with
+// Cluster set to the text position of the first byte of their cluster, marks
+// word separators with GlyphWordSeparator and tabs with GlyphTab, and carries
+// the font's ascent and descent. Spacing is not applied (see ApplySpacing).
+type Shaper interface {
+	Shape(src text.TextReader, ref text.TextRef, params ShapeParams) (GlyphBuffer, error)
+}
+
+// ShapeParams are the inputs to shaping besides the text.
+type ShapeParams struct {
+	Font      FontDescriptor
+	Face      *FontFace      // face selected by itemization, if any
+	Language  string         // BCP 47 language tag; empty if unknown
+	Script    string         // Unicode script name as in unicode.Scripts, e.g. "Latin"; empty to detect
+	Direction text.Direction // direction of the run: DirectionLTR or DirectionRTL
+}
+
+// FontDescriptor selects a font face and size.
+type FontDescriptor struct {
+	Family  string
+	Weight  uint16 // 1..1000; 0 means 400
+	Style   layout.FontStyle
+	Stretch float32 // percentage of the normal width; 0 means 100
+	SizePx  float32
+}
+
+// FixedMetrics are the metrics of a monospace font in em units.
+type FixedMetrics struct {
+	Advance float32 // advance of a narrow character
+	Ascent  float32
+	Descent float32
+}
+
+// DefaultFixedMetrics are used for families missing from a
+// FixedAdvanceShaper's table.
+var DefaultFixedMetrics = FixedMetrics{Advance: 0.6, Ascent: 0.8, Descent: 0.2}
+
+// FixedAdvanceShaper shapes every character with the advance of a monospace
+// font, like a terminal: each rune is one glyph (with the code point as glyph
+// ID), East Asian wide characters take two cells, and combining marks and
+// format characters take none and join the preceding cluster.
+type FixedAdvanceShaper struct {
+	Metrics map[string]FixedMetrics // by font family
+}
+
+var _ Shaper = FixedAdvanceShaper{}
+
+func (s FixedAdvanceShaper) Shape(src text.TextReader, ref text.TextRef, params ShapeParams) (GlyphBuffer, error) {
+	if src == nil || src.ID() != ref.Source {
+		return GlyphBuffer{}, fmt.Errorf("glyphing: text source %d not available for shaping", ref.Source)
+	}
+	m, ok := s.Metrics[params.Font.Family]
+	if !ok {
+		m = DefaultFixedMetrics
+	}
+	size := params.Font.SizePx
+	buf := GlyphBuffer{
+		Text:    ref,
+		Ascent:  m.Ascent * size,
+		Descent: m.Descent * size,
+	}
+	str := src.String(ref.Range)
+	cluster := ref.Range.Start
+	for i, r := range str {
+		pos := ref.Range.Start + text.TextPos(i)
+		cells := cellWidth(r)
+		if cells > 0 || len(buf.Glyphs) == 0 {
+			cluster = pos
+		}
+		g := Glyph{ID: GlyphID(r), Advance: float32(cells) * m.Advance * size, Cluster: cluster, Flags: glyphFlags(r)}
+		buf.Glyphs = append(buf.Glyphs, g)
+	}
+	return buf, nil
+}
+
+// glyphFlags returns the flags of the glyph shaped for r.
+func glyphFlags(r rune) GlyphFlags {
+	switch {
+	case isWordSeparator(r):
+		return GlyphWordSeparator
+	case r == '\t':
+		return GlyphTab
+	}
+	return 0
+}
+
+// isWordSeparator reports the word-separator characters of CSS Text 3 §7.1.
+func isWordSeparator(r rune) bool {
+	switch r {
+	case ' ', '\u00a0', '\u1361', 0x10100, 0x10101, 0x1039F, 0x1091F:
+		return true
+	}
+	return false
+}
+
+// cellWidth returns the number of monospace cells r occupies.
+func cellWidth(r rune) int {
+	switch {
+	case r == utf8.RuneError, r == '\t':
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+		return 0
+	case isWide(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// wideRanges are the main East Asian Wide and Fullwidth ranges (UAX #11).
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},   // Hangul Jamo initials
+	{0x2E80, 0x303E},   // CJK radicals … CJK symbols and punctuation
+	{0x3041, 0x33FF},   // Hiragana … CJK compatibility
+	{0x3400, 0x4DBF},   // CJK extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE30, 0xFE4F},   // CJK compatibility forms
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x1F300, 0x1F64F}, // pictographs and emoticons
+	{0x1F900, 0x1F9FF}, // supplemental symbols and pictographs
+	{0x20000, 0x3FFFD}, // CJK extensions B and later
+}
+
+func isWide(r rune) bool {
+	for _, rg := range wideRanges {
+		if r >= rg.lo && r <= rg.hi {
+			return true
+		}
+	}
+	return false
+}
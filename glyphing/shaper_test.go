@@ -0,0 +1,49 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestFixedAdvanceShaper(t *testing.T) {
+	src := text.NewBuffer(1)
+	src.Append("> ")
+	r := src.Append("ae\u0301 日")
+	ref := text.TextRef{Source: 1, Range: r}
+	shaper := FixedAdvanceShaper{Metrics: map[string]FixedMetrics{"mono": {Advance: 0.5, Ascent: 0.75, Descent: 0.25}}}
+
+	buf, err := shaper.Shape(src, ref, ShapeParams{Font: FontDescriptor{Family: "mono", SizePx: 20}})
+	if err != nil {
+		t.Fatalf("Shape error: %v", err)
+	}
+	if buf.Text != ref || buf.Ascent != 15 || buf.Descent != 5 {
+		t.Fatalf("unexpected buffer header: %+v", buf)
+	}
+	want := []Glyph{
+		{ID: 'a', Advance: 10, Cluster: 2},
+		{ID: 'e', Advance: 10, Cluster: 3},
+		{ID: 0x301, Advance: 0, Cluster: 3},
+		{ID: ' ', Advance: 10, Cluster: 6, Flags: GlyphWordSeparator},
+		{ID: '日', Advance: 20, Cluster: 7},
+	}
+	if len(buf.Glyphs) != len(want) {
+		t.Fatalf("expected %d glyphs, got %+v", len(want), buf.Glyphs)
+	}
+	for i, g := range want {
+		if buf.Glyphs[i] != g {
+			t.Errorf("glyph %d: expected %+v, got %+v", i, g, buf.Glyphs[i])
+		}
+	}
+
+	buf, err = shaper.Shape(src, ref, ShapeParams{Font: FontDescriptor{Family: "serif", SizePx: 10}})
+	if err != nil {
+		t.Fatalf("Shape error: %v", err)
+	}
+	if buf.Glyphs[0].Advance != 6 {
+		t.Fatalf("expected default metrics for unknown family, got advance %v", buf.Glyphs[0].Advance)
+	}
+	if _, err := shaper.Shape(text.NewBuffer(2), ref, ShapeParams{}); err == nil {
+		t.Fatalf("expected error for a foreign text source")
+	}
+}
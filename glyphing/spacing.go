@@ -0,0 +1,66 @@
+package glyphing
+
+import "github.com/npillmayer/css-box-layout/layout"
+
+// Letter- and word-spacing (CSS Text 3 §7) are applied to shaped glyph
+// buffers before line breaking, so that measured line widths include them.
+// Letter-spacing is added after each cluster (a ligature or a base with its
+// marks is one cluster and never spaced internally); word-spacing is added to
+// word separator glyphs. Letter-spacing is not applied at the end of a line:
+// line breakers measure line-final slices with SliceWidth(from, to, true).
+
+// Spacing resolves the letter-spacing and word-spacing of an inline box's
+// style to px.
+func Spacing(style *layout.ComputedStyle) (letter, word float32) {
+	if style == nil {
+		return 0, 0
+	}
+	return spacingPx(style.LetterSpacing, style.FontSizePx), spacingPx(style.WordSpacing, style.FontSizePx)
+}
+
+func spacingPx(l layout.Length, fontSize float32) float32 {
+	switch l.Kind {
+	case layout.LenPx:
+		return l.Value
+	case layout.LenEm:
+		return l.Value * fontSize
+	default:
+		return 0
+	}
+}
+
+// ApplySpacing adjusts the glyph advances of buf to the given letter- and
+// word-spacing. Spacing applied earlier is replaced, not accumulated.
+func ApplySpacing(buf *GlyphBuffer, letter, word float32) {
+	dl, dw := letter-buf.LetterSpacing, word-buf.WordSpacing
+	if dl == 0 && dw == 0 {
+		return
+	}
+	for i := range buf.Glyphs {
+		g := &buf.Glyphs[i]
+		if clusterEnd(buf.Glyphs, i) {
+			g.Advance += dl
+		}
+		if g.Flags&GlyphWordSeparator != 0 {
+			g.Advance += dw
+		}
+	}
+	buf.LetterSpacing, buf.WordSpacing = letter, word
+}
+
+func clusterEnd(glyphs []Glyph, i int) bool {
+	return i == len(glyphs)-1 || glyphs[i+1].Cluster != glyphs[i].Cluster
+}
+
+// SliceWidth returns the advance width of buf.Glyphs[from:to]. If the slice
+// ends a line, the letter-spacing after its last cluster is not counted.
+func (buf GlyphBuffer) SliceWidth(from, to int, lineEnd bool) float32 {
+	var w float32
+	for _, g := range buf.Glyphs[from:to] {
+		w += g.Advance
+	}
+	if lineEnd && to > from {
+		w -= buf.LetterSpacing
+	}
+	return w
+}
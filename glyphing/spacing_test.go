@@ -0,0 +1,47 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/layout"
+)
+
+func TestApplySpacing(t *testing.T) {
+	// "fi a": a ligature "fi" (two glyphs in cluster 0), a space and "a".
+	buf := GlyphBuffer{Glyphs: []Glyph{
+		{Advance: 10, Cluster: 0},
+		{Advance: 0, Cluster: 0},
+		{Advance: 5, Cluster: 2, Flags: GlyphWordSeparator},
+		{Advance: 8, Cluster: 3},
+	}}
+	ApplySpacing(&buf, 2, 3)
+	want := []float32{10, 2, 10, 10}
+	for i, g := range buf.Glyphs {
+		if g.Advance != want[i] {
+			t.Errorf("glyph %d: expected advance %v, got %v", i, want[i], g.Advance)
+		}
+	}
+	if w := buf.SliceWidth(0, 4, false); w != 32 {
+		t.Errorf("expected width 32, got %v", w)
+	}
+	if w := buf.SliceWidth(0, 4, true); w != 30 {
+		t.Errorf("expected line-final width 30, got %v", w)
+	}
+
+	// Re-applying replaces the spacing.
+	ApplySpacing(&buf, 0, 0)
+	if w := buf.SliceWidth(0, 4, false); w != 23 {
+		t.Errorf("expected spacing to be removed, got width %v", w)
+	}
+}
+
+func TestSpacing(t *testing.T) {
+	letter, word := Spacing(&layout.ComputedStyle{
+		FontSizePx:    20,
+		LetterSpacing: layout.Length{Kind: layout.LenEm, Value: 0.1},
+		WordSpacing:   layout.Length{Kind: layout.LenPx, Value: 4},
+	})
+	if letter != 2 || word != 4 {
+		t.Fatalf("expected spacing 2/4, got %v/%v", letter, word)
+	}
+}
@@ -0,0 +1,70 @@
+package glyphing
+
+import (
+	"math"
+
+	"github.com/npillmayer/css-box-layout/layout"
+)
+
+// Tab stops (CSS Text 3 §4.2). Where white-space preserves tabs, a tab
+// advances to the next tab stop. Tab stops are multiples of the tab size from
+// the start edge of the block container's content box, so the advance of a
+// tab is only known once the line breaker has positioned it: shapers give tab
+// glyphs a provisional advance and mark them with GlyphTab, and line breakers
+// call PlaceTabs for each glyph slice they put on a line. Where tabs are not
+// preserved, white-space processing has turned them into spaces before
+// shaping.
+
+// TabStops are the tab stops of a run of text.
+type TabStops struct {
+	Interval   float32 // distance between tab stops; 0 for tab-size 0
+	MinAdvance float32 // a tab which would advance less uses the following stop
+}
+
+// StyleTabStops returns the tab stops of text with the given style. space is
+// the advance of U+0020 in the first available font of style. A tab-size given
+// as a number of spaces includes letter- and word-spacing; tabs advance at
+// least half a space (approximating 0.5ch).
+func StyleTabStops(style *layout.ComputedStyle, space float32) TabStops {
+	stops := TabStops{Interval: 8 * space, MinAdvance: space / 2}
+	if style == nil {
+		return stops
+	}
+	letter, word := Spacing(style)
+	switch size := style.TabSize; size.Kind {
+	case layout.TabSizeInitial:
+		stops.Interval = 8 * (space + letter + word)
+	case layout.TabSizeSpaces:
+		stops.Interval = size.Spaces * (space + letter + word)
+	case layout.TabSizeLength:
+		stops.Interval = spacingPx(size.Length, style.FontSizePx)
+	}
+	return stops
+}
+
+// Next returns the position of the tab stop a tab at x advances to.
+func (t TabStops) Next(x float32) float32 {
+	if t.Interval <= 0 {
+		return x
+	}
+	stop := (float32(math.Floor(float64(x/t.Interval))) + 1) * t.Interval
+	if stop-x < t.MinAdvance {
+		stop += t.Interval
+	}
+	return stop
+}
+
+// PlaceTabs sets the advances of the tab glyphs of buf.Glyphs[from:to] for a
+// slice starting at x, relative to the start edge of the block container's
+// content box (after text-indent), and returns the width of the slice.
+func (buf GlyphBuffer) PlaceTabs(from, to int, x float32, stops TabStops) float32 {
+	start := x
+	for i := from; i < to; i++ {
+		g := &buf.Glyphs[i]
+		if g.Flags&GlyphTab != 0 {
+			g.Advance = stops.Next(x) - x
+		}
+		x += g.Advance
+	}
+	return x - start
+}
@@ -0,0 +1,54 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestPlaceTabs(t *testing.T) {
+	src := text.NewBuffer(1)
+	ref := text.TextRef{Source: 1, Range: src.Append("a\tbc\td")}
+	shaper := FixedAdvanceShaper{Metrics: map[string]FixedMetrics{"mono": {Advance: 1}}}
+	params := ShapeParams{Font: FontDescriptor{Family: "mono", SizePx: 10}}
+
+	tests := []struct {
+		name  string
+		style *layout.ComputedStyle
+		x     float32
+		want  []float32 // advances of the two tabs
+	}{
+		{name: "initial", x: 0, want: []float32{70, 60}},
+		{name: "spaces", style: &layout.ComputedStyle{TabSize: layout.TabSize{Kind: layout.TabSizeSpaces, Spaces: 4}},
+			x: 0, want: []float32{30, 20}},
+		{name: "min_advance", style: &layout.ComputedStyle{TabSize: layout.TabSize{Kind: layout.TabSizeSpaces, Spaces: 4}},
+			x: 28, want: []float32{42, 20}},
+		{name: "letter_spacing", style: &layout.ComputedStyle{TabSize: layout.TabSize{Kind: layout.TabSizeSpaces, Spaces: 2},
+			LetterSpacing: layout.Length{Kind: layout.LenPx, Value: 5}}, x: 0, want: []float32{15, 30}},
+		{name: "length", style: &layout.ComputedStyle{FontSizePx: 10, TabSize: layout.TabSize{Kind: layout.TabSizeLength,
+			Length: layout.Length{Kind: layout.LenEm, Value: 2.5}}}, x: 5, want: []float32{10, 5}},
+		{name: "zero", style: &layout.ComputedStyle{TabSize: layout.TabSize{Kind: layout.TabSizeSpaces}}, x: 0, want: []float32{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := shaper.Shape(src, ref, params)
+			if err != nil {
+				t.Fatalf("Shape error: %v", err)
+			}
+			if buf.Glyphs[1].Flags != GlyphTab || buf.Glyphs[1].Cluster != 1 {
+				t.Fatalf("expected a tab glyph in its own cluster, got %+v", buf.Glyphs[1])
+			}
+			letter, word := Spacing(tt.style)
+			ApplySpacing(&buf, letter, word)
+			w := buf.PlaceTabs(0, len(buf.Glyphs), tt.x, StyleTabStops(tt.style, 10))
+			got := []float32{buf.Glyphs[1].Advance, buf.Glyphs[4].Advance}
+			if got[0] != tt.want[0] || got[1] != tt.want[1] {
+				t.Fatalf("expected tab advances %v, got %v", tt.want, got)
+			}
+			if want := buf.SliceWidth(0, len(buf.Glyphs), false); w != want {
+				t.Fatalf("expected slice width %v, got %v", want, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package glyphing
+
+import (
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// Text overflow (CSS Overflow 4 §3 and §5) is a post-breaking step: lines
+// whose content overflows the line box are cut at a grapheme cluster boundary
+// and end in an ellipsis. This applies to block containers which do not wrap
+// lines (white-space nowrap or pre), have a non-visible overflow and a
+// text-overflow other than clip. With line-clamp, the lines after the clamp
+// are dropped and the last remaining line always ends in an ellipsis.
+
+// EllipsisText returns the string inserted at truncated line ends of a block
+// container: the string of text-overflow, else "…".
+func EllipsisText(block *layout.ComputedStyle) string {
+	if block != nil && block.TextOverflow.Kind == layout.TextOverflowString {
+		return block.TextOverflow.String
+	}
+	return "…"
+}
+
+// ShapeEllipsis shapes s (see EllipsisText) for use with TruncateLines.
+// params should hold the font of the block container.
+func ShapeEllipsis(shaper Shaper, s string, params ShapeParams) (SyntheticGlyphs, error) {
+	src := text.NewBuffer(0)
+	ref := text.TextRef{Source: src.ID(), Range: src.Append(s)}
+	buf, err := shaper.Shape(src, ref, params)
+	if err != nil {
+		return SyntheticGlyphs{}, err
+	}
+	return SyntheticGlyphs{Glyphs: buf.Glyphs, Reason: SynthEllipsis}, nil
+}
+
+// TruncateLines applies text-overflow and line-clamp to the line boxes of a
+// block container and returns the remaining lines. ellipsis is inserted at
+// the end of truncated lines. src holds the text of the shaped buffers and is
+// used to find grapheme cluster boundaries; if it is nil, lines are cut at
+// any glyph cluster boundary. Call it after IndentLines and before
+// ReorderLines.
+func TruncateLines(lines []LineBox, ctx layout.InlineContext, ellipsis SyntheticGlyphs, src text.TextReader, buffers map[layout.NodeID]GlyphBuffer) []LineBox {
+	if ctx.Block == nil || ctx.Block.Style == nil {
+		return lines
+	}
+	style := ctx.Block.Style
+	clamped := style.LineClamp > 0 && len(lines) > style.LineClamp
+	if clamped {
+		lines = lines[:style.LineClamp]
+	}
+	overflow := style.Overflow != layout.OverflowVisible &&
+		style.TextOverflow.Kind != layout.TextOverflowClip && !style.WhiteSpace.Wraps()
+	t := truncator{ellipsis: ellipsis, src: src, buffers: buffers, source: ctx.Block.NodeID}
+	if style.Direction == layout.DirectionRTL {
+		t.level = 1
+	}
+	for _, g := range ellipsis.Glyphs {
+		t.width += g.Advance
+	}
+	for i := range lines {
+		line := &lines[i]
+		last := clamped && i == len(lines)-1
+		if last || overflow && len(line.Frags) > 0 && lineContentEnd(line.Frags) > line.Frame.X+line.Frame.W {
+			t.truncate(line)
+		}
+	}
+	return lines
+}
+
+type truncator struct {
+	ellipsis SyntheticGlyphs
+	width    float32 // advance of the ellipsis
+	level    text.Level
+	source   layout.NodeID
+	src      text.TextReader
+	buffers  map[layout.NodeID]GlyphBuffer
+}
+
+// truncate keeps the fragments of line which fit before the ellipsis, cutting
+// the first one which does not, and appends the ellipsis. The ellipsis takes
+// the vertical position of the fragment it follows.
+func (t truncator) truncate(line *LineBox) {
+	limit := line.Frame.X + line.Frame.W - t.width
+	end, y, h := line.Frame.X, line.Frame.Y, line.Frame.H
+	if len(line.Frags) > 0 {
+		y, h = line.Frags[0].Frame.Y, line.Frags[0].Frame.H
+	}
+	var kept []GlyphFragment
+	for _, f := range line.Frags {
+		fits := f.Frame.X+f.Frame.W <= limit
+		if !fits && f.Kind == FragGlyphSlice {
+			f, fits = t.cut(f, limit)
+		}
+		if !fits {
+			break
+		}
+		kept = append(kept, f)
+		end, y, h = max(end, f.Frame.X+f.Frame.W), f.Frame.Y, f.Frame.H
+	}
+	line.Frags = append(kept, GlyphFragment{
+		SourceID: t.source,
+		Frame:    layout.Rect{X: end, Y: y, W: t.width, H: h},
+		Kind:     FragGlyphSynthetic,
+		Synth:    t.ellipsis,
+		Level:    t.level,
+	})
+}
+
+// cut shortens a glyph fragment to the longest run of whole grapheme clusters
+// ending at or before limit, or returns false if not even the first one fits.
+func (t truncator) cut(f GlyphFragment, limit float32) (GlyphFragment, bool) {
+	buf, ok := t.buffers[f.Slice.BufferOwner]
+	if !ok || f.Slice.From < 0 || f.Slice.To > len(buf.Glyphs) {
+		return f, false
+	}
+	graphemes := t.graphemeStarts(buf)
+	glyphs := buf.Glyphs
+	best := -1
+	x := f.Frame.X
+	for k := f.Slice.From; k < f.Slice.To && x <= limit; k++ {
+		if k > f.Slice.From && glyphs[k].Cluster != glyphs[k-1].Cluster &&
+			(graphemes == nil || graphemes[glyphs[k].Cluster]) {
+			best = k
+		}
+		x += glyphs[k].Advance
+	}
+	if best < 0 {
+		return f, false
+	}
+	f.Frame.W = buf.SliceWidth(f.Slice.From, best, false)
+	f.Slice.To = best
+	if f.Slice.TextRange.End > f.Slice.TextRange.Start {
+		f.Slice.TextRange.End = glyphs[best].Cluster
+	}
+	return f, true
+}
+
+// graphemeStarts returns the grapheme cluster boundaries of the text of buf,
+// or nil if its text is not available.
+func (t truncator) graphemeStarts(buf GlyphBuffer) map[text.TextPos]bool {
+	if t.src == nil || t.src.ID() != buf.Text.Source {
+		return nil
+	}
+	starts := make(map[text.TextPos]bool)
+	for _, pos := range text.GraphemeBoundaries(t.src, buf.Text.Range).All() {
+		starts[pos] = true
+	}
+	return starts
+}
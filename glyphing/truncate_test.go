@@ -0,0 +1,102 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/layout"
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestTruncateLines(t *testing.T) {
+	src := text.NewBuffer(1)
+	ref := text.TextRef{Source: 1, Range: src.Append("ab🇩🇪cd")}
+	buffers := map[layout.NodeID]GlyphBuffer{7: {Text: ref, Glyphs: []Glyph{
+		{ID: 'a', Advance: 10, Cluster: 0},
+		{ID: 'b', Advance: 10, Cluster: 1},
+		{ID: 0x1F1E9, Advance: 10, Cluster: 2},
+		{ID: 0x1F1EA, Advance: 10, Cluster: 6},
+		{ID: 'c', Advance: 10, Cluster: 10},
+		{ID: 'd', Advance: 10, Cluster: 11},
+	}}}
+	ellipsis, err := ShapeEllipsis(FixedAdvanceShaper{}, "…", ShapeParams{Font: FontDescriptor{SizePx: 10}})
+	if err != nil || ellipsis.Reason != SynthEllipsis || len(ellipsis.Glyphs) != 1 {
+		t.Fatalf("unexpected ellipsis %+v (err=%v)", ellipsis, err)
+	}
+	ellipsis.Glyphs[0].Advance = 10
+	line := func(w float32) LineBox {
+		return LineBox{Frame: layout.Rect{W: w, H: 12}, Frags: []GlyphFragment{{
+			SourceID: 7,
+			Frame:    layout.Rect{W: 60, H: 12},
+			Slice:    GlyphSlice{BufferOwner: 7, To: 6, TextRange: ref.Range},
+		}}}
+	}
+	nowrap := &layout.ComputedStyle{
+		Overflow:     layout.OverflowHidden,
+		WhiteSpace:   layout.WhiteSpaceNowrap,
+		TextOverflow: layout.TextOverflow{Kind: layout.TextOverflowEllipsis},
+	}
+
+	tests := []struct {
+		name     string
+		style    *layout.ComputedStyle
+		src      text.TextReader
+		widths   []float32
+		wantLen  int
+		wantCuts []int // glyphs kept on each line; -1 for untruncated lines
+	}{
+		{name: "grapheme_boundary", style: nowrap, src: src, widths: []float32{45}, wantLen: 1, wantCuts: []int{2}},
+		{name: "cluster_boundary_without_text", style: nowrap, widths: []float32{45}, wantLen: 1, wantCuts: []int{3}},
+		{name: "fits", style: nowrap, src: src, widths: []float32{60}, wantLen: 1, wantCuts: []int{-1}},
+		{name: "wrapping", style: &layout.ComputedStyle{Overflow: layout.OverflowHidden,
+			TextOverflow: layout.TextOverflow{Kind: layout.TextOverflowEllipsis}}, src: src, widths: []float32{45}, wantLen: 1, wantCuts: []int{-1}},
+		{name: "overflow_visible", style: &layout.ComputedStyle{WhiteSpace: layout.WhiteSpaceNowrap,
+			TextOverflow: layout.TextOverflow{Kind: layout.TextOverflowEllipsis}}, src: src, widths: []float32{45}, wantLen: 1, wantCuts: []int{-1}},
+		{name: "line_clamp", style: &layout.ComputedStyle{LineClamp: 2}, src: src, widths: []float32{100, 100, 100}, wantLen: 2, wantCuts: []int{-1, 6}},
+		{name: "line_clamp_overflowing", style: &layout.ComputedStyle{LineClamp: 1}, src: src, widths: []float32{65, 100}, wantLen: 1, wantCuts: []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []LineBox
+			for _, w := range tt.widths {
+				lines = append(lines, line(w))
+			}
+			ctx := layout.InlineContext{Block: &layout.LayoutNode{NodeID: 3, Style: tt.style}}
+			lines = TruncateLines(lines, ctx, ellipsis, tt.src, buffers)
+			if len(lines) != tt.wantLen {
+				t.Fatalf("expected %d lines, got %d", tt.wantLen, len(lines))
+			}
+			for i, cut := range tt.wantCuts {
+				frags := lines[i].Frags
+				if cut < 0 {
+					if len(frags) != 1 || frags[0].Slice.To != 6 {
+						t.Errorf("line %d: expected no truncation, got %+v", i, frags)
+					}
+					continue
+				}
+				if len(frags) != 2 || frags[0].Slice.To != cut || frags[0].Frame.W != float32(10*cut) {
+					t.Fatalf("line %d: expected %d glyphs before the ellipsis, got %+v", i, cut, frags)
+				}
+				if cut < 6 {
+					if end := buffers[7].Glyphs[cut].Cluster; frags[0].Slice.TextRange.End != end {
+						t.Errorf("line %d: expected text to end at %d, got %+v", i, end, frags[0].Slice.TextRange)
+					}
+				}
+				e := frags[1]
+				if e.Kind != FragGlyphSynthetic || e.Synth.Reason != SynthEllipsis || e.SourceID != 3 ||
+					e.Frame != (layout.Rect{X: float32(10 * cut), W: 10, H: 12}) {
+					t.Errorf("line %d: unexpected ellipsis fragment %+v", i, e)
+				}
+			}
+		})
+	}
+}
+
+func TestEllipsisText(t *testing.T) {
+	if s := EllipsisText(nil); s != "…" {
+		t.Errorf("expected default ellipsis, got %q", s)
+	}
+	style := &layout.ComputedStyle{TextOverflow: layout.TextOverflow{Kind: layout.TextOverflowString, String: " [more]"}}
+	if s := EllipsisText(style); s != " [more]" {
+		t.Errorf("expected text-overflow string, got %q", s)
+	}
+}
@@ -0,0 +1,65 @@
+package layout
+
+import "github.com/npillmayer/css-box-layout/text"
+
+// Bidirectional text. The Unicode Bidirectional Algorithm (text.BidiLevels)
+// runs over the text of each block container. Following CSS Writing Modes 3
+// §2.4.2, direction and unicode-bidi of inline boxes are expressed by
+// surrounding their text with directional formatting characters.
+
+// Directional formatting characters (UAX #9, Table 1).
+const (
+	bidiLRE = "\u202A"
+	bidiRLE = "\u202B"
+	bidiPDF = "\u202C"
+	bidiLRO = "\u202D"
+	bidiRLO = "\u202E"
+	bidiLRI = "\u2066"
+	bidiRLI = "\u2067"
+	bidiFSI = "\u2068"
+	bidiPDI = "\u2069"
+)
+
+// BidiControls returns the directional formatting characters to insert before
+// and after the content of an inline box with the given style.
+func BidiControls(style *ComputedStyle) (start, end string) {
+	if style == nil {
+		return "", ""
+	}
+	rtl := style.Direction == DirectionRTL
+	pick := func(ltr, rtlCtrl string) string {
+		if rtl {
+			return rtlCtrl
+		}
+		return ltr
+	}
+	switch style.UnicodeBidi {
+	case UnicodeBidiEmbed:
+		return pick(bidiLRE, bidiRLE), bidiPDF
+	case UnicodeBidiIsolate:
+		return pick(bidiLRI, bidiRLI), bidiPDI
+	case UnicodeBidiOverride:
+		return pick(bidiLRO, bidiRLO), bidiPDF
+	case UnicodeBidiIsolateOverride:
+		return bidiFSI + pick(bidiLRO, bidiRLO), bidiPDF + bidiPDI
+	case UnicodeBidiPlaintext:
+		return bidiFSI, bidiPDI
+	default:
+		return "", ""
+	}
+}
+
+// ParagraphDirection returns the base direction of the paragraphs of a block
+// container with the given style.
+func ParagraphDirection(style *ComputedStyle) text.Direction {
+	switch {
+	case style == nil:
+		return text.DirectionLTR
+	case style.UnicodeBidi == UnicodeBidiPlaintext:
+		return text.DirectionAuto
+	case style.Direction == DirectionRTL:
+		return text.DirectionRTL
+	default:
+		return text.DirectionLTR
+	}
+}
@@ -0,0 +1,108 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestBidiControls(t *testing.T) {
+	tests := []struct {
+		name       string
+		style      ComputedStyle
+		start, end string
+	}{
+		{name: "normal", style: ComputedStyle{Direction: DirectionRTL}},
+		{name: "embed_rtl", style: ComputedStyle{Direction: DirectionRTL, UnicodeBidi: UnicodeBidiEmbed}, start: "\u202B", end: "\u202C"},
+		{name: "isolate_ltr", style: ComputedStyle{UnicodeBidi: UnicodeBidiIsolate}, start: "\u2066", end: "\u2069"},
+		{name: "override_rtl", style: ComputedStyle{Direction: DirectionRTL, UnicodeBidi: UnicodeBidiOverride}, start: "\u202E", end: "\u202C"},
+		{name: "isolate_override", style: ComputedStyle{UnicodeBidi: UnicodeBidiIsolateOverride}, start: "\u2068\u202D", end: "\u202C\u2069"},
+		{name: "plaintext", style: ComputedStyle{Direction: DirectionRTL, UnicodeBidi: UnicodeBidiPlaintext}, start: "\u2068", end: "\u2069"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := BidiControls(&tt.style)
+			if start != tt.start || end != tt.end {
+				t.Fatalf("expected %+q/%+q, got %+q/%+q", tt.start, tt.end, start, end)
+			}
+		})
+	}
+	if dir := ParagraphDirection(&ComputedStyle{Direction: DirectionRTL, UnicodeBidi: UnicodeBidiPlaintext}); dir != text.DirectionAuto {
+		t.Fatalf("expected plaintext paragraphs to use auto direction, got %v", dir)
+	}
+}
+
+func TestFlowLayout_RTLBlockPlacement(t *testing.T) {
+	c1 := &LayoutNode{BoxID: 2, Box: BoxBlock}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{c1},
+		Style: &ComputedStyle{Direction: DirectionRTL}}
+	used := UsedValuesTable{
+		root.BoxID: {ContentWidth: 100, Padding: Edges{Left: 5}},
+		c1.BoxID:   {ContentWidth: 50, Margin: Edges{Left: 3, Right: 7}},
+	}
+	res, err := FlowLayout(root, used, fakeInlineLayouter{}, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	if x := res.Geometry[c1.BoxID].Frame.X; x != 5+100-7-50 {
+		t.Fatalf("expected child placed from the right at 48, got %v", x)
+	}
+}
+
+func TestHorizontalMargins(t *testing.T) {
+	tests := []struct {
+		name        string
+		margin      Edges
+		auto        marginAutoFlags
+		rtl         bool
+		left, right float32
+	}{
+		{name: "over_constrained_ltr", margin: Edges{Left: 10, Right: 10}, left: 10, right: 40},
+		{name: "over_constrained_rtl", margin: Edges{Left: 10, Right: 10}, rtl: true, left: 40, right: 10},
+		{name: "both_auto", auto: marginAutoFlags{Left: true, Right: true}, left: 25, right: 25},
+		{name: "left_auto", margin: Edges{Right: 10}, auto: marginAutoFlags{Left: true}, left: 40, right: 10},
+		{name: "right_auto_rtl", margin: Edges{Left: 10}, auto: marginAutoFlags{Right: true}, rtl: true, left: 10, right: 40},
+		{name: "too_wide_rtl", margin: Edges{Left: 30, Right: 30}, auto: marginAutoFlags{Left: true}, rtl: true, left: 20, right: 30},
+	}
+	for _, tt := range tests {
+		left, right := horizontalMargins(tt.margin, tt.auto, 100, 50, tt.rtl)
+		if left != tt.left || right != tt.right {
+			t.Errorf("%s: expected margins %v/%v, got %v/%v", tt.name, tt.left, tt.right, left, right)
+		}
+	}
+}
+
+func TestFlowLayout_AutoMargins(t *testing.T) {
+	c1 := &LayoutNode{BoxID: 2, Box: BoxBlock, Style: &ComputedStyle{
+		Width:  lenPx(40),
+		Margin: EdgeLengths{Left: lenAuto(), Right: lenAuto()},
+	}}
+	c2 := &LayoutNode{BoxID: 3, Box: BoxBlock, Style: &ComputedStyle{
+		Width:  lenPx(40),
+		Margin: EdgeLengths{Left: lenPx(10), Right: lenPx(5)},
+	}}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{c1, c2},
+		Style: &ComputedStyle{Width: lenAuto(), Direction: DirectionRTL}}
+	used, err := ResolveUsedValues(root, ResolveContext{ContainingBlock: Rect{W: 100}})
+	if err != nil {
+		t.Fatalf("ResolveUsedValues error: %v", err)
+	}
+	res, err := FlowLayout(root, used, fakeInlineLayouter{}, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	if x := res.Geometry[c1.BoxID].Frame.X; x != 30 {
+		t.Errorf("expected the auto-margin box centered at 30, got %v", x)
+	}
+	if x := res.Geometry[c2.BoxID].Frame.X; x != 55 {
+		t.Errorf("expected the over-constrained box placed from the right at 55, got %v", x)
+	}
+}
+
+func TestIntrinsicMeasurer_InlineEdgesRTL(t *testing.T) {
+	span := &LayoutNode{BoxID: 2, Box: BoxInline, Style: &ComputedStyle{Direction: DirectionRTL}}
+	used := UsedValuesTable{span.BoxID: {Margin: Edges{Left: 1}, Padding: Edges{Right: 4}}}
+	if start, end := NewIntrinsicMeasurer(fakeInlineIntrinsic{}, used).(IntrinsicContributor).InlineEdges(span); start != 4 || end != 1 {
+		t.Fatalf("expected start/end 4/1 in rtl, got %v/%v", start, end)
+	}
+}
@@ -15,7 +15,8 @@ type LayoutNode struct {
 	FC       FormattingContextKind
 	Style    *ComputedStyle
 	Children []*LayoutNode
-	Text     text.TextRef // For BoxText only (range in base rope)
+	Text     text.TextRef  // For BoxText only (range in base rope)
+	Pseudo   PseudoElement // PseudoNone for DOM-backed boxes, else the generating pseudo-element
 
 	// Computed during layout: border/content rects relative to parent content box.
 	Frame   Rect // border box (recommended)
@@ -31,6 +32,8 @@ const (
 	BoxAnonymousBlock
 	BoxAnonymousInline
 	BoxInlineBlock // atomic inline, lays out children with block rules
+	BoxReplaced    // replaced element (img, video, …): atomic, no children laid out
+	BoxLineBreak   // forced line break (<br>): empty inline-level leaf, see forced_break.go
 )
 
 func IsBlockLevel(kind BoxKind) bool {
@@ -42,6 +45,19 @@ func IsBlockLevel(kind BoxKind) bool {
 	}
 }
 
+// PseudoElement marks boxes created for generated content and ::first-letter.
+// These boxes carry the NodeID of their originating element. ::first-line
+// creates no boxes; its style is ComputedStyle.FirstLine.
+type PseudoElement uint8
+
+const (
+	PseudoNone PseudoElement = iota
+	PseudoBefore
+	PseudoAfter
+	PseudoFirstLetter
+	PseudoFirstLine
+)
+
 type FormattingContextKind uint8
 
 const (
@@ -59,11 +75,15 @@ type BoxMetrics struct {
 
 type LineBox struct {
 	Frame    Rect
-	Baseline float32
+	Baseline float32 // distance from the top of the line box to the root inline box's baseline
 	// Optional:
-	Ascent  float32
-	Descent float32
+	Ascent  float32 // extent above the baseline (Baseline for a fully computed line box)
+	Descent float32 // extent below the baseline
 	Payload any
+
+	// Inlines reports the extent of each inline box (BoxInline) on this line,
+	// in the order the boxes start. FlowLayout derives inline fragments from it.
+	Inlines []InlineExtent
 }
 
 type atomicSizer struct {
@@ -72,40 +92,93 @@ type atomicSizer struct {
 	used      UsedValuesTable
 	geom      LayoutGeometryTable
 	lines     LinesByBlock
+	inlines   *inlineState
+	mode      WritingMode // writing mode of the block container of the inline content
 }
 
 func (a atomicSizer) SizeInlineBlock(n *LayoutNode, maxWidth float32) (float32, float32, error) {
-	if n.Box != BoxInlineBlock {
-		// later: replaced elements etc.
-		return 0, 0, fmt.Errorf("unsupported atomic inline kind")
+	box, err := a.SizeAtomicInline(n, maxWidth)
+	if err != nil {
+		return 0, 0, err
 	}
+	return box.Width, box.Height, nil
+}
 
-	usedW := float32(0)
-	if a.used != nil {
-		if u, ok := a.used[n.BoxID]; ok && u.ContentWidth > 0 {
-			usedW = u.ContentWidth
+func (a atomicSizer) SizeAtomicInline(n *LayoutNode, maxWidth float32) (AtomicBox, error) {
+	u := a.used[n.BoxID]
+	style := usedStyle(n, u)
+	margin := marginsIn(u, a.mode)
+	hMargins := margin.Left + margin.Right
+	vMargins := margin.Top + margin.Bottom
+	if n.Box == BoxReplaced {
+		if err := layoutReplaced(n, maxWidth-hMargins, a.used, a.geom, a.intrinsic); err != nil {
+			return AtomicBox{}, err
 		}
+		a.placeAtMargin(n, u, margin)
+		// Replaced elements have no baseline of their own: use the bottom margin edge.
+		h := n.Frame.H + vMargins
+		return AtomicBox{Width: n.Frame.W + hMargins, Height: h, Baseline: h, Align: style.VerticalAlign}, nil
+	}
+	if n.Box != BoxInlineBlock {
+		return AtomicBox{}, fmt.Errorf("unsupported atomic inline kind")
 	}
-	if usedW == 0 {
-		// Approximate auto width:
+
+	usedW := u.ContentWidth
+	avail := maxWidth - hMargins - (u.Padding.Left + u.Padding.Right + u.Border.Left + u.Border.Right)
+	if u.IntrinsicWidth != nil {
+		var err error
+		if usedW, err = resolveIntrinsicWidth(n, avail, a.used, a.intrinsic); err != nil {
+			return AtomicBox{}, err
+		}
+	} else if u.AutoWidth {
+		minContent, err := a.intrinsic.MinContentWidth(n)
+		if err != nil {
+			return AtomicBox{}, err
+		}
 		maxContent, err := a.intrinsic.MaxContentWidth(n)
 		if err != nil {
-			return 0, 0, err
+			return AtomicBox{}, err
 		}
-		usedW = min(maxWidth, maxContent)
+		usedW = u.Limits.clampWidth(shrinkToFit(minContent, maxContent, avail))
 	}
 
 	// Layout internal contents as a block container with usedW.
-	err := layoutBlockContainer(n, a.used, a.geom, a.lines, a.inline, a.intrinsic)
+	err := layoutBlockContainerWidth(n, usedW, a.used, a.geom, a.lines, a.inlines, a.inline, a.intrinsic)
 	if err != nil {
-		return 0, 0, err
+		return AtomicBox{}, err
+	}
+	a.placeAtMargin(n, u, margin)
+
+	// CSS 2.1 §10.8.1: the baseline of an inline-block is the baseline of its
+	// last line box, unless it has none or its overflow is not visible.
+	h := n.Frame.H + vMargins
+	baseline := h
+	if g := a.geom[n.BoxID]; g.HasBaseline && style.Overflow == OverflowVisible {
+		baseline = margin.Top + g.Baseline
 	}
+	return AtomicBox{Width: n.Frame.W + hMargins, Height: h, Baseline: baseline, Align: style.VerticalAlign}, nil
+}
 
-	// After layoutBlockContainer, n.Frame.H is known; width is usedW.
-	n.Frame.W = usedW
-	n.Content.W = usedW
+// placeAtMargin maps the geometry of an atomic inline to the writing mode of
+// the inline content and moves it from its border-box origin to its
+// margin-box origin.
+func (a atomicSizer) placeAtMargin(n *LayoutNode, u UsedValues, margin Edges) {
+	g := convertGeometry(a.geom[n.BoxID], u.WritingMode, a.mode)
+	g.Frame.X, g.Frame.Y = margin.Left, margin.Top
+	g.Content.X += margin.Left
+	g.Content.Y += margin.Top
+	if n.Box == BoxReplaced {
+		g.Object.X += margin.Left
+		g.Object.Y += margin.Top
+	}
+	a.geom[n.BoxID] = g
+	n.Frame = g.Frame
+	n.Content = g.Content
+}
 
-	return usedW, n.Frame.H, nil
+// shrinkToFit implements the shrink-to-fit width of CSS 2.1 §10.3.9.
+func shrinkToFit(minContent, maxContent, available float32) float32 {
+	return min(max(minContent, available), maxContent)
 }
 
 type LengthKind uint8
@@ -115,6 +188,11 @@ const (
 	LenPercent
 	LenEm
 	LenAuto
+	// Intrinsic size keywords (widths only). Like auto, they cannot be resolved
+	// without measuring the box's contents.
+	LenMinContent
+	LenMaxContent
+	LenFitContent // fit-content(<Value> px), or the fit-content keyword if Value is 0
 )
 
 type Length struct {
@@ -122,6 +200,11 @@ type Length struct {
 	Value float32 // px, percent (0..1), em
 }
 
+// isIntrinsic tells whether l is an intrinsic size keyword.
+func (l Length) isIntrinsic() bool {
+	return l.Kind == LenMinContent || l.Kind == LenMaxContent || l.Kind == LenFitContent
+}
+
 func min(a, b float32) float32 {
 	if a < b {
 		return a
@@ -23,8 +23,8 @@ func newRenderText(id NodeID, data string) *RenderNode {
 	}
 }
 
-func newBoxGenWithRoot(nodeID NodeID) (*boxIDGen, BoxID) {
-	gen := newBoxIDGen()
+func newBoxGenWithRoot(nodeID NodeID) (*builder, BoxID) {
+	gen := newBuilder(BuildOptions{})
 	return gen, gen.newRoot(nodeID)
 }
 
@@ -218,3 +218,70 @@ func TestBuildBlockContainer_MixedInlineBlock(t *testing.T) {
 		t.Fatalf("expected block child preserved")
 	}
 }
+
+func TestBuildInlineFlow_DisplayContentsSplicesChildren(t *testing.T) {
+	childInline := newRenderElement(3, "inline")
+	childBlock := newRenderElement(4, "block")
+	contents := newRenderElement(2, "contents", childInline, childBlock)
+
+	gen, parentBoxID := newBoxGenWithRoot(1)
+	flow, err := buildInlineFlow(gen, contents, parentBoxID)
+	if err != nil {
+		t.Fatalf("buildInlineFlow returned error: %v", err)
+	}
+	if len(flow) != 2 {
+		t.Fatalf("expected 2 flow items, got %d", len(flow))
+	}
+	if flow[0].Kind != FlowInline || flow[0].Node.NodeID != childInline.ID {
+		t.Fatalf("expected first item to be inline child of contents element")
+	}
+	if flow[1].Kind != FlowBlock || flow[1].Node.NodeID != childBlock.ID {
+		t.Fatalf("expected second item to be block child of contents element")
+	}
+	for _, item := range flow {
+		if item.Node.NodeID == contents.ID {
+			t.Fatalf("did not expect a box for the display:contents element")
+		}
+	}
+}
+
+func TestBuildBlockContainer_DisplayContentsBoxIDs(t *testing.T) {
+	// A display:contents wrapper must not disturb BoxID allocation of its children.
+	wrapped := newRenderElement(1, "block",
+		newRenderElement(2, "contents",
+			newRenderElement(3, "inline"),
+			newRenderText(4, "hello"),
+		),
+	)
+	plain := newRenderElement(1, "block",
+		newRenderElement(3, "inline"),
+		newRenderText(4, "hello"),
+	)
+
+	gen1, rootID1 := newBoxGenWithRoot(wrapped.ID)
+	got, err := buildBlockContainer(gen1, wrapped, BoxBlock, rootID1)
+	if err != nil {
+		t.Fatalf("buildBlockContainer returned error: %v", err)
+	}
+	gen2, rootID2 := newBoxGenWithRoot(plain.ID)
+	want, err := buildBlockContainer(gen2, plain, BoxBlock, rootID2)
+	if err != nil {
+		t.Fatalf("buildBlockContainer returned error: %v", err)
+	}
+
+	if len(got.Children) != 1 || got.Children[0].Box != BoxAnonymousInline {
+		t.Fatalf("expected single anonymous inline child")
+	}
+	gotInlines, wantInlines := got.Children[0].Children, want.Children[0].Children
+	if len(gotInlines) != len(wantInlines) {
+		t.Fatalf("expected %d inline children, got %d", len(wantInlines), len(gotInlines))
+	}
+	for i := range gotInlines {
+		if gotInlines[i].BoxID != wantInlines[i].BoxID {
+			t.Fatalf("child %d BoxID = %d, want %d", i, gotInlines[i].BoxID, wantInlines[i].BoxID)
+		}
+		if gotInlines[i].NodeID != wantInlines[i].NodeID {
+			t.Fatalf("child %d NodeID = %d, want %d", i, gotInlines[i].NodeID, wantInlines[i].NodeID)
+		}
+	}
+}
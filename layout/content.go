@@ -0,0 +1,316 @@
+package layout
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// Generated content (::before / ::after).
+//
+// The style adapter exposes pseudo-element styles through RenderNode.PseudoStyle.
+// A pseudo-element generates a box if its "content" computes to something other
+// than "none"/"normal". The box becomes the first (::before) or last (::after)
+// child of its originating element, carries the element's NodeID, and is marked
+// with LayoutNode.Pseudo. Its text is appended to BuildOptions.Text.
+// Counters and quotes are handled in counters.go.
+
+type contentKind uint8
+
+const (
+	contentString contentKind = iota
+	contentAttr
+	contentCounter
+	contentCounters
+	contentOpenQuote
+	contentCloseQuote
+	contentNoOpenQuote
+	contentNoCloseQuote
+)
+
+type contentItem struct {
+	kind  contentKind
+	text  string // string value, attribute name or counter name
+	sep   string // separator for counters()
+	style string // counter style for counter()/counters()
+}
+
+// Builds the flow item for one pseudo-element of r, if it generates a box.
+func buildPseudoFlow(b *builder, r *RenderNode, pseudo PseudoElement, parentBoxID BoxID) ([]FlowItem, error) {
+	items, ok := parseContent(r.PseudoStyle(pseudo, "content"))
+	if !ok {
+		return nil, nil
+	}
+	display := r.PseudoStyle(pseudo, "display")
+	if display == "none" {
+		return nil, nil
+	}
+	if display == "" {
+		display = "inline"
+	}
+	get := func(prop string) string { return r.PseudoStyle(pseudo, prop) }
+	b.counters.enter(get)
+	defer b.counters.leave()
+	quotes := get("quotes")
+	if quotes == "" {
+		quotes = r.ComputedStyle("quotes")
+	}
+	content := b.generatedString(r, items, quotes)
+
+	switch display {
+	case "inline":
+		flow, err := b.generatedText(content, r.ID, pseudo, parentBoxID)
+		if err != nil {
+			return nil, err
+		}
+		return []FlowItem{InlineItem(&LayoutNode{
+			BoxID:    b.newChild(parentBoxID),
+			NodeID:   r.ID,
+			Box:      BoxInline,
+			FC:       FCInline,
+			Pseudo:   pseudo,
+			Children: inlineChildren(flow),
+		})}, nil
+	case "inline-block", "block":
+		box := BoxBlock
+		if display == "inline-block" {
+			box = BoxInlineBlock
+		}
+		boxID := b.newChild(parentBoxID)
+		flow, err := b.generatedText(content, r.ID, pseudo, boxID)
+		if err != nil {
+			return nil, err
+		}
+		children, err := normalizeBlockChildren(b.boxIDGen, flow, boxID)
+		if err != nil {
+			return nil, err
+		}
+		node := &LayoutNode{
+			BoxID:    boxID,
+			NodeID:   r.ID,
+			Box:      box,
+			FC:       FCBlock,
+			Pseudo:   pseudo,
+			Children: children,
+		}
+		if box == BoxBlock {
+			return []FlowItem{BlockItem(node)}, nil
+		}
+		return []FlowItem{InlineItem(node)}, nil
+	default:
+		return nil, errNotImplemented
+	}
+}
+
+// errNoTextStore is returned for generated text if BuildOptions.Text is nil:
+// without a store its TextRef could not be told from those of the document.
+var errNoTextStore = errors.New("generated content needs BuildOptions.Text")
+
+// Creates the BoxText leaf for generated text s, storing s in the builder's text store.
+func (b *builder) generatedText(s string, owner NodeID, pseudo PseudoElement, parentBoxID BoxID) ([]FlowItem, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if b.text == nil {
+		return nil, errNoTextStore
+	}
+	leaf := &LayoutNode{
+		BoxID:  b.newChild(parentBoxID),
+		NodeID: owner,
+		Box:    BoxText,
+		Pseudo: pseudo,
+		Text:   text.TextRef{Source: b.text.ID(), Range: b.text.Append(s)},
+	}
+	b.texts[leaf.BoxID] = s
+	return []FlowItem{InlineItem(leaf)}, nil
+}
+
+// Evaluates a content list. Counters and quote depth are taken from (and
+// updated in) the builder's document-order state.
+func (b *builder) generatedString(r *RenderNode, items []contentItem, quotes string) string {
+	var sb strings.Builder
+	for _, item := range items {
+		switch item.kind {
+		case contentString:
+			sb.WriteString(item.text)
+		case contentAttr:
+			sb.WriteString(attrValue(r.HTMLNode(), item.text))
+		case contentCounter:
+			sb.WriteString(b.counters.counter(item.text, item.style))
+		case contentCounters:
+			sb.WriteString(b.counters.counterChain(item.text, item.sep, item.style))
+		default:
+			sb.WriteString(b.counters.quote(item.kind, quotes))
+		}
+	}
+	return sb.String()
+}
+
+func attrValue(n *html.Node, name string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// === Parsing ==========================================================
+
+// parseContent parses a computed "content" value. ok is false if no box is
+// generated ("none", "normal" or empty). Unsupported items are skipped, as is
+// the alternative text after a "/", which is for accessibility only.
+func parseContent(value string) (items []contentItem, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "none" || value == "normal" {
+		return nil, false
+	}
+	for _, tok := range tokenizeContent(value) {
+		switch {
+		case tok.value == "/" && !tok.isString:
+			return items, true
+		case tok.isString:
+			items = append(items, contentItem{kind: contentString, text: tok.value})
+		case tok.function == "attr" && len(tok.args) > 0:
+			items = append(items, contentItem{kind: contentAttr, text: tok.args[0].value})
+		case tok.function == "counter" && len(tok.args) > 0:
+			item := contentItem{kind: contentCounter, text: tok.args[0].value, style: "decimal"}
+			if len(tok.args) > 1 {
+				item.style = tok.args[1].value
+			}
+			items = append(items, item)
+		case tok.function == "counters" && len(tok.args) > 1:
+			item := contentItem{kind: contentCounters, text: tok.args[0].value, sep: tok.args[1].value, style: "decimal"}
+			if len(tok.args) > 2 {
+				item.style = tok.args[2].value
+			}
+			items = append(items, item)
+		case tok.value == "open-quote":
+			items = append(items, contentItem{kind: contentOpenQuote})
+		case tok.value == "close-quote":
+			items = append(items, contentItem{kind: contentCloseQuote})
+		case tok.value == "no-open-quote":
+			items = append(items, contentItem{kind: contentNoOpenQuote})
+		case tok.value == "no-close-quote":
+			items = append(items, contentItem{kind: contentNoCloseQuote})
+		}
+	}
+	return items, true
+}
+
+// contentToken is a string, an identifier, or a function with its
+// comma-separated arguments.
+type contentToken struct {
+	value    string
+	isString bool
+	function string
+	args     []contentToken
+}
+
+func tokenizeContent(s string) []contentToken {
+	var tokens []contentToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == ',':
+			i++
+		case c == '"' || c == '\'':
+			str, n := scanCSSString(s[i:])
+			tokens = append(tokens, contentToken{value: str, isString: true})
+			i += n
+		case c == '/':
+			tokens = append(tokens, contentToken{value: "/"})
+			i++
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n,\"'()/", rune(s[j])) {
+				j++
+			}
+			if j == i { // stray parenthesis
+				i++
+				continue
+			}
+			name := s[i:j]
+			if j < len(s) && s[j] == '(' {
+				end := matchingParen(s, j)
+				tokens = append(tokens, contentToken{
+					function: strings.ToLower(name),
+					args:     tokenizeContent(s[j+1 : end]),
+				})
+				i = end + 1
+				continue
+			}
+			tokens = append(tokens, contentToken{value: name})
+			i = j
+		}
+	}
+	return tokens
+}
+
+// scanCSSString reads a quoted CSS string at the start of s, resolving escapes.
+// It returns the unquoted value and the number of bytes consumed.
+func scanCSSString(s string) (string, int) {
+	quote := s[0]
+	var sb strings.Builder
+	i := 1
+	for i < len(s) {
+		c := s[i]
+		if c == quote {
+			return sb.String(), i + 1
+		}
+		if c != '\\' || i+1 >= len(s) {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+		i++
+		j := i
+		for j < len(s) && j-i < 6 && isHexDigit(s[j]) {
+			j++
+		}
+		if j == i { // escaped literal character (or escaped newline)
+			if s[i] != '\n' {
+				sb.WriteByte(s[i])
+			}
+			i++
+			continue
+		}
+		cp, _ := strconv.ParseUint(s[i:j], 16, 32)
+		sb.WriteRune(rune(cp))
+		if j < len(s) && s[j] == ' ' {
+			j++
+		}
+		i = j
+	}
+	return sb.String(), len(s)
+}
+
+func matchingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			_, n := scanCSSString(s[i:])
+			i += n - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,128 @@
+package layout
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestParseContent(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+		items []contentItem
+	}{
+		{value: "", ok: false},
+		{value: "none", ok: false},
+		{value: "normal", ok: false},
+		{value: `""`, ok: true, items: []contentItem{{kind: contentString}}},
+		{value: `"a" 'b'`, ok: true, items: []contentItem{
+			{kind: contentString, text: "a"},
+			{kind: contentString, text: "b"},
+		}},
+		{value: `"\"x\\" "\2192 y"`, ok: true, items: []contentItem{
+			{kind: contentString, text: `"x\`},
+			{kind: contentString, text: "→y"},
+		}},
+		{value: `"(" attr(title) ")"`, ok: true, items: []contentItem{
+			{kind: contentString, text: "("},
+			{kind: contentAttr, text: "title"},
+			{kind: contentString, text: ")"},
+		}},
+		// Alternative text is not rendered.
+		{value: `"x" / "alt"`, ok: true, items: []contentItem{{kind: contentString, text: "x"}}},
+		{value: `open-quote/"alt" attr(title)`, ok: true, items: []contentItem{{kind: contentOpenQuote}}},
+		{value: `"a/b"`, ok: true, items: []contentItem{{kind: contentString, text: "a/b"}}},
+	}
+	for _, tt := range tests {
+		items, ok := parseContent(tt.value)
+		if ok != tt.ok {
+			t.Fatalf("parseContent(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+		}
+		if len(items) != len(tt.items) {
+			t.Fatalf("parseContent(%q) = %+v, want %+v", tt.value, items, tt.items)
+		}
+		for i := range items {
+			if items[i] != tt.items[i] {
+				t.Fatalf("parseContent(%q)[%d] = %+v, want %+v", tt.value, i, items[i], tt.items[i])
+			}
+		}
+	}
+}
+
+func TestBuildBlockContainer_PseudoElements(t *testing.T) {
+	parent := newRenderElement(1, "block", newRenderText(2, "body"))
+	parent.HTML.Attr = []html.Attribute{{Key: "title", Val: "T"}}
+	parent.PseudoStyles = map[PseudoElement]map[string]string{
+		PseudoBefore: {"content": `"[" attr(title) "]"`},
+		PseudoAfter:  {"content": `"!"`, "display": "block"},
+	}
+
+	buf := text.NewBuffer(7)
+	b := newBuilder(BuildOptions{Text: buf})
+	node, err := buildBlockContainer(b, parent, BoxBlock, b.newRoot(parent.ID))
+	if err != nil {
+		t.Fatalf("buildBlockContainer returned error: %v", err)
+	}
+	if len(node.Children) != 2 {
+		t.Fatalf("expected anonymous block + ::after block, got %d children", len(node.Children))
+	}
+	after := node.Children[1]
+	if after.Box != BoxBlock || after.Pseudo != PseudoAfter || after.NodeID != parent.ID {
+		t.Fatalf("expected ::after block box owned by parent, got %+v", after)
+	}
+
+	inlines := node.Children[0].Children[0].Children
+	if len(inlines) != 2 {
+		t.Fatalf("expected ::before inline and DOM text, got %d inline children", len(inlines))
+	}
+	before := inlines[0]
+	if before.Box != BoxInline || before.Pseudo != PseudoBefore || before.NodeID != parent.ID {
+		t.Fatalf("expected ::before inline box as first child, got %+v", before)
+	}
+	if inlines[1].Pseudo != PseudoNone || inlines[1].NodeID != 2 {
+		t.Fatalf("expected DOM text to be unmarked")
+	}
+	if len(before.Children) != 1 || before.Children[0].Box != BoxText {
+		t.Fatalf("expected ::before to contain a single text box")
+	}
+	ref := before.Children[0].Text
+	if ref.Source != buf.ID() || buf.String(ref.Range) != "[T]" {
+		t.Fatalf("generated text = %q in source %d, want %q", buf.String(ref.Range), ref.Source, "[T]")
+	}
+}
+
+func TestBuildBlockContainer_PseudoElementsWithoutTextStore(t *testing.T) {
+	parent := newRenderElement(1, "block", newRenderText(2, "body"))
+	parent.PseudoStyles = map[PseudoElement]map[string]string{PseudoBefore: {"content": `"x"`}}
+	b := newBuilder(BuildOptions{})
+	if _, err := buildBlockContainer(b, parent, BoxBlock, b.newRoot(parent.ID)); !errors.Is(err, errNoTextStore) {
+		t.Fatalf("expected errNoTextStore for generated text without a text store, got %v", err)
+	}
+	// Empty generated content needs no store.
+	parent.PseudoStyles[PseudoBefore]["content"] = `""`
+	b = newBuilder(BuildOptions{})
+	if _, err := buildBlockContainer(b, parent, BoxBlock, b.newRoot(parent.ID)); err != nil {
+		t.Fatalf("buildBlockContainer returned error: %v", err)
+	}
+}
+
+func TestBuildInlineFlow_PseudoContentNone(t *testing.T) {
+	parent := newRenderElement(1, "inline", newRenderText(2, "x"))
+	parent.PseudoStyles = map[PseudoElement]map[string]string{
+		PseudoBefore: {"content": "none"},
+		PseudoAfter:  {"content": `"a"`, "display": "none"},
+	}
+
+	gen, parentBoxID := newBoxGenWithRoot(1)
+	flow, err := buildInlineFlow(gen, parent, parentBoxID)
+	if err != nil {
+		t.Fatalf("buildInlineFlow returned error: %v", err)
+	}
+	if len(flow) != 1 || len(flow[0].Node.Children) != 1 {
+		t.Fatalf("expected no generated boxes")
+	}
+}
@@ -0,0 +1,324 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CSS counters and quotes for generated content.
+//
+// Counters are evaluated in document order while building the layout tree, so
+// numbering depends only on the render tree and is identical across rebuilds.
+// A counter instance created by counter-reset on an element is in scope for the
+// element, its descendants, and its following siblings with their descendants.
+
+type counterInstance struct {
+	value int
+	depth int // depth of the element which instantiated the counter
+}
+
+type counterState struct {
+	depth      int
+	counters   map[string][]counterInstance // innermost instance last
+	quoteDepth int
+}
+
+func newCounterState() *counterState {
+	return &counterState{counters: make(map[string][]counterInstance)}
+}
+
+// enter opens the scope of an element (or pseudo-element) and applies its
+// counter properties in the order reset, increment, set.
+func (cs *counterState) enter(style func(string) string) {
+	cs.depth++
+	for _, c := range parseCounterList(style("counter-reset"), 0) {
+		cs.reset(c.name, c.value)
+	}
+	for _, c := range parseCounterList(style("counter-increment"), 1) {
+		inst := cs.innermost(c.name)
+		inst.value += c.value
+	}
+	for _, c := range parseCounterList(style("counter-set"), 0) {
+		inst := cs.innermost(c.name)
+		inst.value = c.value
+	}
+}
+
+// leave closes the scope of the current element, dropping counter instances
+// created by its children.
+func (cs *counterState) leave() {
+	for name, stack := range cs.counters {
+		n := len(stack)
+		for n > 0 && stack[n-1].depth > cs.depth {
+			n--
+		}
+		if n == 0 {
+			delete(cs.counters, name)
+		} else {
+			cs.counters[name] = stack[:n]
+		}
+	}
+	cs.depth--
+}
+
+func (cs *counterState) reset(name string, value int) {
+	stack := cs.counters[name]
+	if n := len(stack); n > 0 && stack[n-1].depth == cs.depth {
+		// A preceding sibling's counter: siblings do not nest.
+		stack = stack[:n-1]
+	}
+	cs.counters[name] = append(stack, counterInstance{value: value, depth: cs.depth})
+}
+
+// innermost returns the innermost instance of counter name, instantiating one
+// on the current element if none is in scope.
+func (cs *counterState) innermost(name string) *counterInstance {
+	if len(cs.counters[name]) == 0 {
+		cs.reset(name, 0)
+	}
+	stack := cs.counters[name]
+	return &stack[len(stack)-1]
+}
+
+// counter implements counter(name, style).
+func (cs *counterState) counter(name, style string) string {
+	return formatCounter(cs.innermost(name).value, style)
+}
+
+// counterChain implements counters(name, separator, style).
+func (cs *counterState) counterChain(name, sep, style string) string {
+	cs.innermost(name)
+	stack := cs.counters[name]
+	parts := make([]string, len(stack))
+	for i, inst := range stack {
+		parts[i] = formatCounter(inst.value, style)
+	}
+	return strings.Join(parts, sep)
+}
+
+// quote implements open-quote/close-quote and their no-* variants.
+func (cs *counterState) quote(kind contentKind, quotes string) string {
+	pairs := parseQuotes(quotes)
+	pair := func() [2]string {
+		if len(pairs) == 0 {
+			return [2]string{}
+		}
+		if cs.quoteDepth >= len(pairs) {
+			return pairs[len(pairs)-1]
+		}
+		return pairs[cs.quoteDepth]
+	}
+	switch kind {
+	case contentOpenQuote:
+		s := pair()[0]
+		cs.quoteDepth++
+		return s
+	case contentNoOpenQuote:
+		cs.quoteDepth++
+	case contentCloseQuote:
+		if cs.quoteDepth > 0 {
+			cs.quoteDepth--
+			return pair()[1]
+		}
+	case contentNoCloseQuote:
+		if cs.quoteDepth > 0 {
+			cs.quoteDepth--
+		}
+	}
+	return ""
+}
+
+// === Parsing ==========================================================
+
+type counterOp struct {
+	name  string
+	value int
+}
+
+// parseCounterList parses "none" or a list of counter names, each optionally
+// followed by an integer (defaulting to dflt).
+func parseCounterList(value string, dflt int) []counterOp {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || fields[0] == "none" {
+		return nil
+	}
+	var ops []counterOp
+	for i := 0; i < len(fields); i++ {
+		op := counterOp{name: fields[i], value: dflt}
+		if i+1 < len(fields) {
+			if v, err := strconv.Atoi(fields[i+1]); err == nil {
+				op.value = v
+				i++
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+var defaultQuotes = [][2]string{{"“", "”"}, {"‘", "’"}}
+
+// parseQuotes parses the computed value of "quotes" into open/close pairs.
+func parseQuotes(value string) [][2]string {
+	value = strings.TrimSpace(value)
+	if value == "none" {
+		return nil
+	}
+	var strs []string
+	for _, tok := range tokenizeContent(value) {
+		if tok.isString {
+			strs = append(strs, tok.value)
+		}
+	}
+	if len(strs) < 2 {
+		return defaultQuotes
+	}
+	pairs := make([][2]string, 0, len(strs)/2)
+	for i := 0; i+1 < len(strs); i += 2 {
+		pairs = append(pairs, [2]string{strs[i], strs[i+1]})
+	}
+	return pairs
+}
+
+// === Counter styles ===================================================
+
+// formatCounter renders value in one of the predefined counter styles.
+// Unknown styles and values out of a style's range fall back to decimal.
+func formatCounter(value int, style string) string {
+	switch style {
+	case "none":
+		return ""
+	case "disc":
+		return "•"
+	case "circle":
+		return "◦"
+	case "square":
+		return "▪"
+	case "decimal-leading-zero":
+		// Pad 2 "0"; the negative sign goes outside the padding.
+		if value < 0 && value > -10 {
+			return "-0" + strconv.Itoa(-value)
+		}
+		if value >= 0 && value < 10 {
+			return "0" + strconv.Itoa(value)
+		}
+	case "lower-roman":
+		if s, ok := formatRoman(value); ok {
+			return strings.ToLower(s)
+		}
+	case "upper-roman":
+		if s, ok := formatRoman(value); ok {
+			return s
+		}
+	case "lower-alpha", "lower-latin":
+		if s, ok := formatAlphabetic(value, []rune("abcdefghijklmnopqrstuvwxyz")); ok {
+			return s
+		}
+	case "upper-alpha", "upper-latin":
+		if s, ok := formatAlphabetic(value, []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ")); ok {
+			return s
+		}
+	case "lower-greek":
+		if s, ok := formatAlphabetic(value, []rune("αβγδεζηθικλμνξοπρστυφχψω")); ok {
+			return s
+		}
+	case "cjk-decimal":
+		return formatCJKDecimal(value)
+	case "cjk-ideographic", "trad-chinese-informal":
+		if s, ok := formatCJKIdeographic(value); ok {
+			return s
+		}
+		return formatCJKDecimal(value)
+	}
+	return strconv.Itoa(value)
+}
+
+func formatRoman(value int) (string, bool) {
+	if value < 1 || value > 3999 {
+		return "", false
+	}
+	numerals := []struct {
+		v int
+		s string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+	var sb strings.Builder
+	for _, n := range numerals {
+		for value >= n.v {
+			sb.WriteString(n.s)
+			value -= n.v
+		}
+	}
+	return sb.String(), true
+}
+
+// formatAlphabetic implements the "alphabetic" counter system (a, b, …, z, aa, ab, …).
+func formatAlphabetic(value int, digits []rune) (string, bool) {
+	if value < 1 {
+		return "", false
+	}
+	n := len(digits)
+	var out []rune
+	for value > 0 {
+		value--
+		out = append([]rune{digits[value%n]}, out...)
+		value /= n
+	}
+	return string(out), true
+}
+
+var cjkDigits = []rune("〇一二三四五六七八九")
+
+func formatCJKDecimal(value int) string {
+	s := strconv.Itoa(value)
+	var sb strings.Builder
+	for _, c := range s {
+		if c == '-' {
+			sb.WriteRune(c)
+			continue
+		}
+		sb.WriteRune(cjkDigits[c-'0'])
+	}
+	return sb.String()
+}
+
+// formatCJKIdeographic renders 0..9999 with Chinese informal numerals, e.g. 二十一.
+func formatCJKIdeographic(value int) (string, bool) {
+	if value < 0 || value > 9999 {
+		return "", false
+	}
+	if value == 0 {
+		return "零", true
+	}
+	units := []rune{0, '十', '百', '千'}
+	var out []rune
+	zero := false
+	for pos := 3; pos >= 0; pos-- {
+		d := value / pow10(pos) % 10
+		if d == 0 {
+			zero = len(out) > 0
+			continue
+		}
+		if zero {
+			out = append(out, '零')
+			zero = false
+		}
+		if !(d == 1 && pos == 1 && len(out) == 0) { // 十 rather than 一十
+			out = append(out, cjkDigits[d])
+		}
+		if pos > 0 {
+			out = append(out, units[pos])
+		}
+	}
+	return string(out), true
+}
+
+func pow10(n int) int {
+	p := 1
+	for ; n > 0; n-- {
+		p *= 10
+	}
+	return p
+}
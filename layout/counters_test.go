@@ -0,0 +1,118 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestFormatCounter(t *testing.T) {
+	tests := []struct {
+		value int
+		style string
+		want  string
+	}{
+		{7, "decimal", "7"},
+		{-3, "decimal", "-3"},
+		{7, "decimal-leading-zero", "07"},
+		{-5, "decimal-leading-zero", "-05"},
+		{-12, "decimal-leading-zero", "-12"},
+		{1994, "upper-roman", "MCMXCIV"},
+		{14, "lower-roman", "xiv"},
+		{0, "lower-roman", "0"},
+		{1, "lower-alpha", "a"},
+		{28, "upper-latin", "AB"},
+		{3, "lower-greek", "γ"},
+		{205, "cjk-decimal", "二〇五"},
+		{10, "cjk-ideographic", "十"},
+		{21, "cjk-ideographic", "二十一"},
+		{1010, "cjk-ideographic", "一千零一十"},
+		{5, "disc", "•"},
+		{5, "unknown-style", "5"},
+	}
+	for _, tt := range tests {
+		if got := formatCounter(tt.value, tt.style); got != tt.want {
+			t.Errorf("formatCounter(%d, %q) = %q, want %q", tt.value, tt.style, got, tt.want)
+		}
+	}
+}
+
+// Collects the generated text of all ::before boxes in document order.
+func collectBeforeText(n *LayoutNode, buf *text.Buffer, out []string) []string {
+	if n == nil {
+		return out
+	}
+	if n.Box == BoxText && n.Pseudo == PseudoBefore {
+		out = append(out, buf.String(n.Text.Range))
+	}
+	for _, c := range n.Children {
+		out = collectBeforeText(c, buf, out)
+	}
+	return out
+}
+
+func newListItem(id NodeID, children ...*RenderNode) *RenderNode {
+	li := newRenderElement(id, "block", children...)
+	li.PseudoStyles = map[PseudoElement]map[string]string{
+		PseudoBefore: {"content": `counters(item, ".") " "`, "counter-increment": "item"},
+	}
+	return li
+}
+
+func newList(id NodeID, children ...*RenderNode) *RenderNode {
+	ol := newRenderElement(id, "block", children...)
+	ol.Styles["counter-reset"] = "item"
+	return ol
+}
+
+func TestBuildLayoutTree_NestedCounters(t *testing.T) {
+	root := newList(1,
+		newListItem(2),
+		newListItem(3,
+			newList(4,
+				newListItem(5),
+				newListItem(6),
+			),
+		),
+		newListItem(7),
+	)
+
+	for run := 0; run < 2; run++ { // numbering must be reproducible across rebuilds
+		buf := text.NewBuffer(1)
+		tree, err := BuildLayoutTree(root, BuildOptions{Text: buf})
+		if err != nil {
+			t.Fatalf("BuildLayoutTree returned error: %v", err)
+		}
+		got := collectBeforeText(tree, buf, nil)
+		want := []string{"1 ", "2 ", "2.1 ", "2.2 ", "3 "}
+		if len(got) != len(want) {
+			t.Fatalf("generated %q, want %q", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("generated %q, want %q", got, want)
+			}
+		}
+	}
+}
+
+func TestBuildLayoutTree_NestedQuotes(t *testing.T) {
+	q := func(id NodeID, children ...*RenderNode) *RenderNode {
+		n := newRenderElement(id, "inline", children...)
+		n.PseudoStyles = map[PseudoElement]map[string]string{
+			PseudoBefore: {"content": "open-quote"},
+			PseudoAfter:  {"content": "close-quote"},
+		}
+		n.Styles["quotes"] = `"«" "»" "‹" "›"`
+		return n
+	}
+	root := newRenderElement(1, "block", q(2, q(3, newRenderText(4, "x"))))
+
+	buf := text.NewBuffer(1)
+	if _, err := BuildLayoutTree(root, BuildOptions{Text: buf}); err != nil {
+		t.Fatalf("BuildLayoutTree returned error: %v", err)
+	}
+	if got := buf.String(text.TextRange{Start: 0, End: buf.LenBytes()}); got != "«‹›»" {
+		t.Fatalf("generated quotes = %q, want %q", got, "«‹›»")
+	}
+}
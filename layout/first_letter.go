@@ -0,0 +1,232 @@
+package layout
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// ::first-letter and ::first-line (CSS Pseudo-Elements 4 §2).
+//
+// A block container whose RenderNode has ::first-letter styles gets an inline
+// box around the first typographic letter unit of its first formatted line:
+// a letter, digit or symbol grapheme cluster with adjacent punctuation. The
+// box has Pseudo PseudoFirstLetter and the NodeID of the block container, as
+// has the text leaf inside it. The text leaf holding the letter is split; the
+// remainder keeps its BoxID. The letter is searched depth-first through inline
+// boxes and into the first in-flow block child; atomic inlines and <br> end
+// the search. A floated ::first-letter stays an inline box, as floats are not
+// laid out yet; use initial-letter (initial_letter.go) for drop caps.
+//
+// ::first-line creates no boxes. Its style is ComputedStyle.FirstLine of the
+// block container, resolved for inline content with FirstLineStyle and
+// applied by re-shaping the first line after line breaking.
+
+func hasPseudoStyle(r *RenderNode, pseudo PseudoElement) bool {
+	return r != nil && len(r.PseudoStyles[pseudo]) > 0
+}
+
+type letterSearch uint8
+
+const (
+	letterContinue letterSearch = iota // only collapsible white space so far
+	letterFound
+	letterStop // content without a letter unit comes first
+)
+
+// Splits the first letter in the subtree of n into a ::first-letter box owned
+// by owner.
+func (b *builder) splitFirstLetter(n *LayoutNode, owner NodeID) letterSearch {
+	for i := 0; i < len(n.Children); i++ {
+		c := n.Children[i]
+		switch c.Box {
+		case BoxText:
+			s, ok := b.texts[c.BoxID]
+			if !ok || isWhiteSpace(s) {
+				continue
+			}
+			start, end, ok := firstLetterUnit(s)
+			if !ok {
+				return letterStop
+			}
+			n.Children = append(n.Children[:i:i], append(b.splitLetterLeaf(n, c, s, start, end, owner), n.Children[i+1:]...)...)
+			return letterFound
+		case BoxInline, BoxAnonymousInline:
+			if c.Pseudo == PseudoFirstLetter {
+				return letterStop // already split for a descendant block container
+			}
+			if res := b.splitFirstLetter(c, owner); res != letterContinue {
+				return res
+			}
+		case BoxBlock, BoxAnonymousBlock:
+			if res := b.splitFirstLetter(c, owner); res != letterContinue {
+				return res
+			}
+		default:
+			return letterStop
+		}
+	}
+	return letterContinue
+}
+
+// Replaces leaf (a child of parent) by an optional white space leaf, the
+// ::first-letter box holding s[start:end] and the remainder of leaf.
+func (b *builder) splitLetterLeaf(parent, leaf *LayoutNode, s string, start, end int, owner NodeID) []*LayoutNode {
+	var out []*LayoutNode
+	pos := leaf.Text.Range.Start
+	sub := func(from, to int) text.TextRef {
+		return text.TextRef{Source: leaf.Text.Source, Range: text.TextRange{Start: pos + uint64(from), End: pos + uint64(to)}}
+	}
+	if start > 0 {
+		ws := &LayoutNode{BoxID: b.newChild(parent.BoxID), NodeID: leaf.NodeID, Box: BoxText, Pseudo: leaf.Pseudo, Text: sub(0, start)}
+		b.texts[ws.BoxID] = s[:start]
+		out = append(out, ws)
+	}
+	boxID := b.newChild(parent.BoxID)
+	letter := &LayoutNode{BoxID: b.newChild(boxID), NodeID: owner, Box: BoxText, Pseudo: PseudoFirstLetter, Text: sub(start, end)}
+	b.texts[letter.BoxID] = s[start:end]
+	out = append(out, &LayoutNode{
+		BoxID:    boxID,
+		NodeID:   owner,
+		Box:      BoxInline,
+		FC:       FCInline,
+		Pseudo:   PseudoFirstLetter,
+		Children: []*LayoutNode{letter},
+	})
+	if end < len(s) {
+		leaf.Text = sub(end, len(s))
+		b.texts[leaf.BoxID] = s[end:]
+		out = append(out, leaf)
+	} else {
+		delete(b.texts, leaf.BoxID)
+	}
+	return out
+}
+
+// firstLetterUnit returns the byte range of the first typographic letter unit
+// of s after leading white space: grapheme clusters of punctuation, one
+// cluster starting with a letter, number or symbol, and the closing
+// punctuation following it. ok is false if s has no such unit at its start.
+func firstLetterUnit(s string) (start, end int, ok bool) {
+	src := text.NewBuffer(0)
+	bounds := text.GraphemeBoundaries(src, src.Append(s)).All()
+	i := 0
+	for i+1 < len(bounds) && isWhiteSpace(s[bounds[i]:bounds[i+1]]) {
+		i++
+	}
+	if i+1 >= len(bounds) {
+		return 0, 0, false
+	}
+	start = int(bounds[i])
+	for i+1 < len(bounds) && clusterIn(s[bounds[i]:bounds[i+1]], unicode.Ps, unicode.Pe, unicode.Pi, unicode.Pf, unicode.Po) {
+		i++
+	}
+	if i+1 >= len(bounds) || !clusterIn(s[bounds[i]:bounds[i+1]], unicode.L, unicode.N, unicode.S) {
+		return 0, 0, false
+	}
+	i++
+	for i+1 < len(bounds) && clusterIn(s[bounds[i]:bounds[i+1]], unicode.Pe, unicode.Pi, unicode.Pf, unicode.Po) {
+		i++
+	}
+	return start, int(bounds[i]), true
+}
+
+// clusterIn reports whether the first rune of a grapheme cluster is in one of
+// the given categories.
+func clusterIn(cluster string, categories ...*unicode.RangeTable) bool {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	return unicode.IsOneOf(categories, r)
+}
+
+func isWhiteSpace(s string) bool {
+	return strings.TrimLeft(s, " \t\n\r\f") == ""
+}
+
+// InheritedProps is a set of the inherited font and spacing properties which
+// ::first-line can set.
+type InheritedProps uint8
+
+const (
+	PropFontFamily InheritedProps = 1 << iota
+	PropFontSize
+	PropFontWeight
+	PropFontStyle
+	PropFontStretch
+	PropLineHeight
+	PropLetterSpacing
+	PropWordSpacing
+)
+
+// FirstLineStyle returns the style of inline content with style style on the
+// first formatted line of block. The ::first-line style block.FirstLine takes
+// the place of block for inheritance: walking the Parent chain from style up
+// to block, the properties an element does not set in Specified are inherited
+// again, and a font-size with a FontSizeScale is computed again from the
+// inherited one. Content whose Parent chain does not reach block keeps its
+// style. FirstLineStyle returns style itself if nothing differs.
+func FirstLineStyle(block, style *ComputedStyle) *ComputedStyle {
+	if block == nil || block.FirstLine == nil || style == nil {
+		return style
+	}
+	var chain []*ComputedStyle // from style up to, not including, block
+	for s := style; s != block; s = s.Parent {
+		if s == nil {
+			return style
+		}
+		chain = append(chain, s)
+	}
+	b := *block
+	inheritFirstLine(&b, block.FirstLine, 0)
+	b.FirstLine = nil
+	parent := &b
+	for i := len(chain) - 1; i >= 0; i-- {
+		s := *chain[i]
+		s.Parent = parent
+		inheritFirstLine(&s, parent, s.Specified)
+		parent = &s
+	}
+	if sameInheritedProps(parent, style) {
+		return style
+	}
+	return parent
+}
+
+// inheritFirstLine sets the properties of s which are not in own to those of
+// parent.
+func inheritFirstLine(s, parent *ComputedStyle, own InheritedProps) {
+	if own&PropFontFamily == 0 {
+		s.FontFamily = parent.FontFamily
+	}
+	if own&PropFontSize == 0 {
+		s.FontSizePx = parent.FontSizePx
+	} else if s.FontSizeScale > 0 {
+		s.FontSizePx = s.FontSizeScale * parent.FontSizePx
+	}
+	if own&PropFontWeight == 0 {
+		s.FontWeight = parent.FontWeight
+	}
+	if own&PropFontStyle == 0 {
+		s.FontStyle = parent.FontStyle
+	}
+	if own&PropFontStretch == 0 {
+		s.FontStretch = parent.FontStretch
+	}
+	if own&PropLineHeight == 0 {
+		s.LineHeight = parent.LineHeight
+	}
+	if own&PropLetterSpacing == 0 {
+		s.LetterSpacing = parent.LetterSpacing
+	}
+	if own&PropWordSpacing == 0 {
+		s.WordSpacing = parent.WordSpacing
+	}
+}
+
+func sameInheritedProps(a, b *ComputedStyle) bool {
+	return slices.Equal(a.FontFamily, b.FontFamily) && a.FontSizePx == b.FontSizePx &&
+		a.FontWeight == b.FontWeight && a.FontStyle == b.FontStyle && a.FontStretch == b.FontStretch &&
+		a.LineHeight == b.LineHeight && a.LetterSpacing == b.LetterSpacing && a.WordSpacing == b.WordSpacing
+}
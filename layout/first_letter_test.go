@@ -0,0 +1,151 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func TestBuildBlockContainer_FirstLetter(t *testing.T) {
+	parent := newRenderElement(1, "block",
+		newRenderElement(2, "inline", newRenderText(3, "  “Hello")),
+	)
+	parent.PseudoStyles = map[PseudoElement]map[string]string{
+		PseudoFirstLetter: {"font-size": "3em"},
+	}
+	gen, rootBoxID := newBoxGenWithRoot(parent.ID)
+	node, err := buildBlockContainer(gen, parent, BoxBlock, rootBoxID)
+	if err != nil {
+		t.Fatalf("buildBlockContainer returned error: %v", err)
+	}
+	span := node.Children[0].Children[0]
+	if span.NodeID != 2 || len(span.Children) != 3 {
+		t.Fatalf("expected the span to hold 3 children, got %+v", span)
+	}
+	ws, letter, rest := span.Children[0], span.Children[1], span.Children[2]
+	if ws.Box != BoxText || ws.NodeID != 3 || ws.Text.Range != (text.TextRange{Start: 0, End: 2}) {
+		t.Errorf("expected a leading white space leaf, got %+v", ws)
+	}
+	if letter.Box != BoxInline || letter.Pseudo != PseudoFirstLetter || letter.NodeID != 1 || len(letter.Children) != 1 {
+		t.Fatalf("expected a ::first-letter box, got %+v", letter)
+	}
+	if leaf := letter.Children[0]; leaf.NodeID != 1 || leaf.Pseudo != PseudoFirstLetter ||
+		leaf.Text.Range != (text.TextRange{Start: 2, End: 6}) {
+		t.Errorf("expected the letter leaf to hold “H, got %+v", leaf)
+	}
+	if rest.NodeID != 3 || rest.Pseudo != PseudoNone || rest.Text.Range != (text.TextRange{Start: 6, End: 10}) {
+		t.Errorf("expected the remainder leaf to hold ello, got %+v", rest)
+	}
+	if ids := map[BoxID]bool{ws.BoxID: true, letter.BoxID: true, letter.Children[0].BoxID: true, rest.BoxID: true}; len(ids) != 4 {
+		t.Errorf("expected distinct BoxIDs, got %v", ids)
+	}
+}
+
+func TestBuildBlockContainer_FirstLetterSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() *RenderNode
+		want  int // number of ::first-letter boxes
+	}{
+		{"first_block_child", func() *RenderNode {
+			return newRenderElement(1, "block", newRenderElement(2, "block", newRenderText(3, "ab")))
+		}, 1},
+		{"skips_empty_block", func() *RenderNode {
+			return newRenderElement(1, "block", newRenderElement(2, "block", newRenderText(3, " ")), newRenderText(4, "ab"))
+		}, 1},
+		{"inline_block_first", func() *RenderNode {
+			return newRenderElement(1, "block", newRenderElement(2, "inline-block", newRenderText(3, "ab")), newRenderText(4, "cd"))
+		}, 0},
+		{"punctuation_only", func() *RenderNode {
+			return newRenderElement(1, "block", newRenderText(2, "..."), newRenderText(3, "ab"))
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.build()
+			r.PseudoStyles = map[PseudoElement]map[string]string{PseudoFirstLetter: {"color": "red"}}
+			gen, rootBoxID := newBoxGenWithRoot(r.ID)
+			node, err := buildBlockContainer(gen, r, BoxBlock, rootBoxID)
+			if err != nil {
+				t.Fatalf("buildBlockContainer returned error: %v", err)
+			}
+			if got := countFirstLetterBoxes(node); got != tt.want {
+				t.Fatalf("expected %d ::first-letter boxes, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func countFirstLetterBoxes(n *LayoutNode) int {
+	count := 0
+	if n.Box == BoxInline && n.Pseudo == PseudoFirstLetter {
+		count++
+	}
+	for _, c := range n.Children {
+		count += countFirstLetterBoxes(c)
+	}
+	return count
+}
+
+func TestFirstLetterUnit(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		ok         bool
+	}{
+		{"Hello", 0, 1, true},
+		{"  x", 2, 3, true},
+		{"«Je", 0, 3, true},
+		{"(1) a", 0, 3, true},
+		{"été", 0, 3, true},
+		{"$5", 0, 1, true},
+		{"...", 0, 0, false},
+		{" ", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, ok := firstLetterUnit(tt.s)
+		if start != tt.start || end != tt.end || ok != tt.ok {
+			t.Errorf("firstLetterUnit(%+q) = %d, %d, %v; want %d, %d, %v", tt.s, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
+
+func TestFirstLineStyle(t *testing.T) {
+	block := &ComputedStyle{FontSizePx: 16, FontFamily: []string{"serif"}}
+	block.FirstLine = &ComputedStyle{FontSizePx: 20, FontFamily: []string{"serif"}, FontWeight: 700}
+	if s := FirstLineStyle(block, block); s == block || s.FontSizePx != 20 || s.FontWeight != 700 || s.FirstLine != nil {
+		t.Fatalf("expected the first-line style for the block's text, got %+v", s)
+	}
+	em := &ComputedStyle{FontSizePx: 16, FontFamily: []string{"serif"}, FontStyle: FontStyleItalic,
+		Parent: block, Specified: PropFontStyle}
+	if s := FirstLineStyle(block, em); s.FontSizePx != 20 || s.FontStyle != FontStyleItalic || s.FontWeight != 700 {
+		t.Fatalf("expected inherited values only to be replaced, got %+v", s)
+	}
+	// A font size equal to the block's is still the element's own.
+	code := &ComputedStyle{FontSizePx: 16, FontFamily: []string{"monospace"},
+		Parent: block, Specified: PropFontFamily | PropFontSize}
+	if s := FirstLineStyle(block, code); s.FontSizePx != 16 || s.FontFamily[0] != "monospace" || s.FontWeight != 700 {
+		t.Fatalf("expected own font size and family to be kept, got %+v", s)
+	}
+	// em sizes are computed again from the first-line font size, also for
+	// content inheriting them.
+	big := &ComputedStyle{FontSizePx: 24, FontFamily: []string{"serif"},
+		Parent: block, Specified: PropFontSize, FontSizeScale: 1.5}
+	inner := &ComputedStyle{FontSizePx: 24, FontFamily: []string{"serif"}, Parent: big}
+	if s := FirstLineStyle(block, inner); s.FontSizePx != 30 || s.FontWeight != 700 || s.Parent.FontSizePx != 30 {
+		t.Fatalf("expected 1.5em of the first-line font size, got %+v", s)
+	}
+	own := &ComputedStyle{FontSizePx: 13, FontFamily: []string{"monospace"}, FontWeight: 700,
+		Parent: block, Specified: PropFontFamily | PropFontSize | PropFontWeight}
+	if s := FirstLineStyle(block, own); s != own {
+		t.Fatalf("expected the style itself if nothing differs, got %+v", s)
+	}
+	if s := FirstLineStyle(block, &ComputedStyle{FontSizePx: 16}); s.FontSizePx != 16 {
+		t.Fatalf("expected content outside the block to keep its style, got %+v", s)
+	}
+	plain := &ComputedStyle{FontSizePx: 16}
+	if s := FirstLineStyle(plain, plain); s != plain {
+		t.Fatalf("expected the style itself without ::first-line")
+	}
+}
@@ -21,34 +21,46 @@ type FlowItem struct {
 func InlineItem(n *LayoutNode) FlowItem { return FlowItem{Kind: FlowInline, Node: n} }
 func BlockItem(n *LayoutNode) FlowItem  { return FlowItem{Kind: FlowBlock, Node: n} }
 
+// builder carries the state of a single BuildLayoutTree run.
+type builder struct {
+	*boxIDGen
+	text     text.TextStore   // receives generated content; may be nil
+	counters *counterState    // CSS counters and quote depth, in document order
+	texts    map[BoxID]string // text of BoxText leaves, for ::first-letter
+}
+
+func newBuilder(opts BuildOptions) *builder {
+	return &builder{boxIDGen: newBoxIDGen(), text: opts.Text, counters: newCounterState(), texts: make(map[BoxID]string)}
+}
+
 // Entry point for a block container (BoxBlock / BoxAnonymousBlock / BoxInlineBlock):
-func buildBlockContainer(gen *boxIDGen, r *RenderNode, box BoxKind, boxID BoxID) (*LayoutNode, error) {
+func buildBlockContainer(b *builder, r *RenderNode, box BoxKind, boxID BoxID) (*LayoutNode, error) {
 	if r == nil {
 		return nil, nil
 	}
-	flow := make([]FlowItem, 0, len(r.Children()))
-	for _, child := range r.Children() {
-		items, err := buildInlineFlow(gen, child, boxID)
-		if err != nil {
-			return nil, err
-		}
-		flow = append(flow, items...)
+	flow, err := buildElementFlow(b, r, boxID)
+	if err != nil {
+		return nil, err
 	}
-	children, err := normalizeBlockChildren(gen, flow, boxID)
+	children, err := normalizeBlockChildren(b.boxIDGen, flow, boxID)
 	if err != nil {
 		return nil, err
 	}
-	return &LayoutNode{
+	node := &LayoutNode{
 		BoxID:    boxID,
 		NodeID:   r.ID,
 		Box:      box,
 		FC:       FCBlock,
 		Children: children,
-	}, nil
+	}
+	if hasPseudoStyle(r, PseudoFirstLetter) {
+		b.splitFirstLetter(node, r.ID)
+	}
+	return node, nil
 }
 
 // Builds an inline-level subtree, but may return hoisted blocks as FlowBlock items:
-func buildInlineFlow(gen *boxIDGen, r *RenderNode, parentBoxID BoxID) ([]FlowItem, error) {
+func buildInlineFlow(b *builder, r *RenderNode, parentBoxID BoxID) ([]FlowItem, error) {
 	if r == nil {
 		return nil, nil
 	}
@@ -63,30 +75,37 @@ func buildInlineFlow(gen *boxIDGen, r *RenderNode, parentBoxID BoxID) ([]FlowIte
 		if text == nil {
 			return nil, nil
 		}
-		text.BoxID = gen.newChild(parentBoxID)
+		text.BoxID = b.newChild(parentBoxID)
 		text.NodeID = r.ID
+		b.texts[text.BoxID] = r.HTMLNode().Data
 		return []FlowItem{InlineItem(text)}, nil
 	}
 
 	if display == "" {
 		display = "inline"
 	}
+	if isReplacedElement(r.HTMLNode()) {
+		return buildReplaced(b, r, display, parentBoxID), nil
+	}
+	if isLineBreakElement(r.HTMLNode()) {
+		return buildLineBreak(b, r, display, parentBoxID), nil
+	}
 
 	switch display {
+	case "contents":
+		// No box for the element itself: its children's flow items are spliced
+		// into the parent's flow, getting their BoxIDs from the parent box.
+		return buildElementFlow(b, r, parentBoxID)
 	case "inline":
-		flow := make([]FlowItem, 0, len(r.Children()))
-		for _, child := range r.Children() {
-			items, err := buildInlineFlow(gen, child, parentBoxID)
-			if err != nil {
-				return nil, err
-			}
-			flow = append(flow, items...)
+		flow, err := buildElementFlow(b, r, parentBoxID)
+		if err != nil {
+			return nil, err
 		}
 		if containsBlockFlow(flow) {
 			proto := &LayoutNode{NodeID: r.ID, Box: BoxInline, FC: FCInline}
-			return wrapInlineRunsForElement(gen, proto, flow, parentBoxID), nil
+			return wrapInlineRunsForElement(b.boxIDGen, proto, flow, parentBoxID), nil
 		}
-		boxID := gen.newChild(parentBoxID)
+		boxID := b.newChild(parentBoxID)
 		return []FlowItem{InlineItem(&LayoutNode{
 			BoxID:    boxID,
 			NodeID:   r.ID,
@@ -95,15 +114,15 @@ func buildInlineFlow(gen *boxIDGen, r *RenderNode, parentBoxID BoxID) ([]FlowIte
 			Children: inlineChildren(flow),
 		})}, nil
 	case "inline-block":
-		boxID := gen.newChild(parentBoxID)
-		node, err := buildBlockContainer(gen, r, BoxInlineBlock, boxID)
+		boxID := b.newChild(parentBoxID)
+		node, err := buildBlockContainer(b, r, BoxInlineBlock, boxID)
 		if err != nil {
 			return nil, err
 		}
 		return []FlowItem{InlineItem(node)}, nil
 	case "block":
-		boxID := gen.newChild(parentBoxID)
-		node, err := buildBlockContainer(gen, r, BoxBlock, boxID)
+		boxID := b.newChild(parentBoxID)
+		node, err := buildBlockContainer(b, r, BoxBlock, boxID)
 		if err != nil {
 			return nil, err
 		}
@@ -113,6 +132,41 @@ func buildInlineFlow(gen *boxIDGen, r *RenderNode, parentBoxID BoxID) ([]FlowIte
 	}
 }
 
+// Builds the flow items of an element's content: ::before, children, ::after.
+func buildElementFlow(b *builder, r *RenderNode, parentBoxID BoxID) ([]FlowItem, error) {
+	b.counters.enter(r.ComputedStyle)
+	defer b.counters.leave()
+	before, err := buildPseudoFlow(b, r, PseudoBefore, parentBoxID)
+	if err != nil {
+		return nil, err
+	}
+	flow, err := buildChildrenFlow(b, r, parentBoxID)
+	if err != nil {
+		return nil, err
+	}
+	after, err := buildPseudoFlow(b, r, PseudoAfter, parentBoxID)
+	if err != nil {
+		return nil, err
+	}
+	if len(before) == 0 && len(after) == 0 {
+		return flow, nil
+	}
+	return append(append(before, flow...), after...), nil
+}
+
+// Builds the flow items of all children of r, in order, parented to parentBoxID.
+func buildChildrenFlow(b *builder, r *RenderNode, parentBoxID BoxID) ([]FlowItem, error) {
+	flow := make([]FlowItem, 0, len(r.Children()))
+	for _, child := range r.Children() {
+		items, err := buildInlineFlow(b, child, parentBoxID)
+		if err != nil {
+			return nil, err
+		}
+		flow = append(flow, items...)
+	}
+	return flow, nil
+}
+
 func buildText(r *RenderNode) *LayoutNode { // returns BoxText leaf
 	if r == nil || r.HTMLNode() == nil {
 		return nil
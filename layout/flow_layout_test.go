@@ -1,23 +1,31 @@
 package layout
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 type fakeInlineLayouter struct {
 	lines []LineBox
 }
 
-func (f fakeInlineLayouter) LayoutInline(inlineRoot *LayoutNode, maxWidth float32, atomic AtomicSizer) ([]LineBox, error) {
+func (f fakeInlineLayouter) LayoutInline(inlineRoot *LayoutNode, maxWidth float32, atomic AtomicSizer, ctx InlineContext) ([]LineBox, error) {
 	return append([]LineBox(nil), f.lines...), nil
 }
 
 type fakeIntrinsic struct {
 	maxContent float32
+	minContent float32
 }
 
 func (f fakeIntrinsic) MaxContentWidth(node *LayoutNode) (float32, error) {
 	return f.maxContent, nil
 }
 
+func (f fakeIntrinsic) MinContentWidth(node *LayoutNode) (float32, error) {
+	return f.minContent, nil
+}
+
 func TestFlowLayout_BlockStacking(t *testing.T) {
 	root := &LayoutNode{BoxID: 1, Box: BoxBlock}
 	c1 := &LayoutNode{BoxID: 2, Box: BoxBlock}
@@ -135,7 +143,7 @@ func TestAtomicSizer_InlineBlockWidth(t *testing.T) {
 		t.Fatalf("width = %v, want 120", w)
 	}
 
-	a.used[inlineBlock.BoxID] = UsedValues{ContentWidth: 0}
+	a.used[inlineBlock.BoxID] = UsedValues{AutoWidth: true}
 	w, _, err = a.SizeInlineBlock(inlineBlock, 150)
 	if err != nil {
 		t.Fatalf("SizeInlineBlock error: %v", err)
@@ -143,6 +151,15 @@ func TestAtomicSizer_InlineBlockWidth(t *testing.T) {
 	if w != 150 {
 		t.Fatalf("width = %v, want 150", w)
 	}
+
+	a.used[inlineBlock.BoxID] = UsedValues{ContentWidth: 0}
+	w, _, err = a.SizeInlineBlock(inlineBlock, 150)
+	if err != nil {
+		t.Fatalf("SizeInlineBlock error: %v", err)
+	}
+	if w != 0 {
+		t.Fatalf("width with explicit width 0 = %v, want 0", w)
+	}
 }
 
 func TestFlowLayout_LinesNotStoredForAnonymousBlock(t *testing.T) {
@@ -171,3 +188,154 @@ func TestFlowLayout_LinesNotStoredForAnonymousBlock(t *testing.T) {
 		t.Fatalf("did not expect lines stored for anonymous block owner")
 	}
 }
+
+func TestFlowLayout_AspectRatioHeight(t *testing.T) {
+	inf := float32(math.Inf(1))
+	tests := []struct {
+		name       string
+		used       UsedValues
+		style      *ComputedStyle
+		lineHeight float32
+		want       float32
+	}{
+		{name: "from_width", used: UsedValues{ContentWidth: 160, AspectRatio: 16.0 / 9}, lineHeight: 10, want: 90},
+		{name: "content_overflows", used: UsedValues{ContentWidth: 160, AspectRatio: 16.0 / 9}, lineHeight: 120, want: 120},
+		{
+			name:       "overflow_hidden_keeps_ratio",
+			used:       UsedValues{ContentWidth: 160, AspectRatio: 16.0 / 9},
+			style:      &ComputedStyle{Overflow: OverflowHidden},
+			lineHeight: 120,
+			want:       90,
+		},
+		{
+			name:       "max_height",
+			used:       UsedValues{ContentWidth: 160, AspectRatio: 16.0 / 9, Limits: &SizeLimits{MaxWidth: inf, MaxHeight: 50}},
+			lineHeight: 10,
+			want:       50,
+		},
+		{name: "definite_height_wins", used: UsedValues{ContentWidth: 160, ContentHeight: 30, HasHeight: true, AspectRatio: 1}, lineHeight: 10, want: 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inlineRoot := &LayoutNode{BoxID: 2, Box: BoxAnonymousInline}
+			root := &LayoutNode{BoxID: 1, Box: BoxBlock, Style: tt.style, Children: []*LayoutNode{inlineRoot}}
+			used := UsedValuesTable{root.BoxID: tt.used}
+			lines := []LineBox{{Frame: Rect{H: tt.lineHeight}}}
+
+			res, err := FlowLayout(root, used, fakeInlineLayouter{lines: lines}, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+			if err != nil {
+				t.Fatalf("FlowLayout error: %v", err)
+			}
+			if h := res.Geometry[root.BoxID].Content.H; h != tt.want {
+				t.Fatalf("content height = %v, want %v", h, tt.want)
+			}
+		})
+	}
+}
+
+func TestAtomicSizer_InlineBlockBaseline(t *testing.T) {
+	inlineRoot := &LayoutNode{BoxID: 2, Box: BoxAnonymousInline}
+	inlineBlock := &LayoutNode{BoxID: 1, Box: BoxInlineBlock, Children: []*LayoutNode{inlineRoot}}
+	lines := []LineBox{
+		{Frame: Rect{Y: 0, H: 10}, Baseline: 8},
+		{Frame: Rect{Y: 10, H: 10}, Baseline: 8},
+	}
+	a := atomicSizer{
+		inline:    fakeInlineLayouter{lines: lines},
+		intrinsic: fakeIntrinsic{},
+		used: UsedValuesTable{
+			inlineBlock.BoxID: {
+				ContentWidth: 50,
+				Padding:      Edges{Top: 3, Bottom: 3},
+				Margin:       Edges{Bottom: 4},
+			},
+		},
+		geom:  make(LayoutGeometryTable),
+		lines: make(LinesByBlock),
+	}
+
+	box, err := a.SizeAtomicInline(inlineBlock, 100)
+	if err != nil {
+		t.Fatalf("SizeAtomicInline error: %v", err)
+	}
+	if box.Height != 30 || box.Baseline != 21 {
+		t.Fatalf("height/baseline = %v/%v, want 30/21", box.Height, box.Baseline)
+	}
+
+	inlineBlock.Style = &ComputedStyle{Overflow: OverflowHidden}
+	box, err = a.SizeAtomicInline(inlineBlock, 100)
+	if err != nil {
+		t.Fatalf("SizeAtomicInline error: %v", err)
+	}
+	if box.Baseline != 30 {
+		t.Fatalf("baseline with overflow:hidden = %v, want bottom margin edge 30", box.Baseline)
+	}
+}
+
+func TestFlowLayout_BaselineFromNestedBlock(t *testing.T) {
+	inlineRoot := &LayoutNode{BoxID: 3, Box: BoxAnonymousInline}
+	anon := &LayoutNode{BoxID: 2, Box: BoxAnonymousBlock, Children: []*LayoutNode{inlineRoot}}
+	empty := &LayoutNode{BoxID: 4, Box: BoxBlock}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{anon, empty}}
+	used := UsedValuesTable{
+		root.BoxID: {ContentWidth: 100, Border: Edges{Top: 2}},
+		anon.BoxID: {ContentWidth: 100, Margin: Edges{Top: 5}},
+	}
+	lines := []LineBox{{Frame: Rect{H: 12}, Baseline: 9}}
+
+	res, err := FlowLayout(root, used, fakeInlineLayouter{lines: lines}, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	g := res.Geometry[root.BoxID]
+	if !g.HasBaseline || g.Baseline != 16 {
+		t.Fatalf("root baseline = %v (has %v), want 16", g.Baseline, g.HasBaseline)
+	}
+}
+
+func TestAtomicSizer_InlineBlockShrinkToFit(t *testing.T) {
+	tests := []struct {
+		name       string
+		min, max   float32
+		avail      float32
+		wantWidth  float32
+		wantMargin float32
+	}{
+		{name: "fits_max_content", min: 20, max: 60, avail: 200, wantWidth: 60},
+		{name: "available", min: 20, max: 300, avail: 200, wantWidth: 200},
+		{name: "min_content_overflows", min: 250, max: 300, avail: 200, wantWidth: 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inlineBlock := &LayoutNode{BoxID: 1, Box: BoxInlineBlock}
+			u := UsedValues{
+				AutoWidth: true,
+				Margin:    Edges{Left: 5, Right: 5, Top: 2},
+				Padding:   Edges{Left: 10, Right: 10},
+				Border:    Edges{Left: 1, Right: 1},
+			}
+			a := atomicSizer{
+				inline:    fakeInlineLayouter{},
+				intrinsic: fakeIntrinsic{minContent: tt.min, maxContent: tt.max},
+				used:      UsedValuesTable{inlineBlock.BoxID: u},
+				geom:      make(LayoutGeometryTable),
+				lines:     make(LinesByBlock),
+			}
+			// Available content width is maxWidth minus 32px of margins, padding and border.
+			box, err := a.SizeAtomicInline(inlineBlock, tt.avail+32)
+			if err != nil {
+				t.Fatalf("SizeAtomicInline error: %v", err)
+			}
+			if box.Width != tt.wantWidth+32 {
+				t.Fatalf("margin box width = %v, want %v", box.Width, tt.wantWidth+32)
+			}
+			g := a.geom[inlineBlock.BoxID]
+			if g.Frame != (Rect{X: 5, Y: 2, W: tt.wantWidth + 22}) {
+				t.Fatalf("frame = %+v", g.Frame)
+			}
+			if g.Content != (Rect{X: 16, Y: 2, W: tt.wantWidth}) {
+				t.Fatalf("content = %+v", g.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package layout
+
+import (
+	"golang.org/x/net/html"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+// Forced line breaks (CSS Text 3 §5.1). A <br> element becomes a BoxLineBreak
+// leaf; where white-space preserves them, segment breaks in the text of BoxText
+// leaves act the same way. An inline layouter ends the current line box after
+// a forced break even if the line is empty, so consecutive forced breaks
+// produce empty line boxes: these hold just the strut and have its height.
+// The break itself takes no space on the line.
+
+func isLineBreakElement(n *html.Node) bool {
+	return n != nil && n.Type == html.ElementNode && n.Data == "br"
+}
+
+// Builds the flow item for a <br> element. Its children are not rendered.
+func buildLineBreak(b *builder, r *RenderNode, display string, parentBoxID BoxID) []FlowItem {
+	if display == "contents" { // behaves as display:none for <br>
+		return nil
+	}
+	return []FlowItem{InlineItem(&LayoutNode{
+		BoxID:  b.newChild(parentBoxID),
+		NodeID: r.ID,
+		Box:    BoxLineBreak,
+	})}
+}
+
+// SegmentBreaks returns the positions after the segment breaks (LF, CR or
+// CR LF) in the text of ref, if white-space ws preserves them. Inline
+// layouters break lines at these positions.
+func SegmentBreaks(src text.TextReader, ref text.TextRef, ws WhiteSpace) []text.TextPos {
+	if !ws.PreservesNewlines() {
+		return nil
+	}
+	s := src.String(ref.Range)
+	var breaks []text.TextPos
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case '\n':
+		default:
+			continue
+		}
+		breaks = append(breaks, ref.Range.Start+text.TextPos(i+1))
+	}
+	return breaks
+}
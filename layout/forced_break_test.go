@@ -0,0 +1,79 @@
+package layout
+
+import (
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
+
+func newRenderBr(id NodeID, display string) *RenderNode {
+	return &RenderNode{
+		ID:     id,
+		HTML:   &html.Node{Type: html.ElementNode, Data: "br"},
+		Styles: map[string]string{"display": display},
+	}
+}
+
+func TestBuildBlockContainer_LineBreaks(t *testing.T) {
+	parent := newRenderElement(1, "block",
+		newRenderText(2, "a"),
+		newRenderBr(3, ""),
+		newRenderBr(4, "inline"),
+		newRenderBr(5, "contents"),
+		newRenderBr(6, "none"),
+		newRenderText(7, "b"),
+	)
+	gen, rootBoxID := newBoxGenWithRoot(parent.ID)
+	node, err := buildBlockContainer(gen, parent, BoxBlock, rootBoxID)
+	if err != nil {
+		t.Fatalf("buildBlockContainer returned error: %v", err)
+	}
+	if len(node.Children) != 1 || node.Children[0].Box != BoxAnonymousInline {
+		t.Fatalf("expected a single anonymous inline child")
+	}
+	inlines := node.Children[0].Children
+	want := []struct {
+		id  NodeID
+		box BoxKind
+	}{{2, BoxText}, {3, BoxLineBreak}, {4, BoxLineBreak}, {7, BoxText}}
+	if len(inlines) != len(want) {
+		t.Fatalf("expected %d inline children, got %d", len(want), len(inlines))
+	}
+	for i, w := range want {
+		if inlines[i].NodeID != w.id || inlines[i].Box != w.box || len(inlines[i].Children) != 0 {
+			t.Errorf("child %d: expected node %d of kind %d, got %+v", i, w.id, w.box, inlines[i])
+		}
+	}
+}
+
+func TestSegmentBreaks(t *testing.T) {
+	src := text.NewBuffer(1)
+	src.Append("> ")
+	ref := text.TextRef{Source: 1, Range: src.Append("a\n\nb\r\nc\rd")}
+	tests := []struct {
+		ws   WhiteSpace
+		want []text.TextPos
+	}{
+		{WhiteSpaceNormal, nil},
+		{WhiteSpaceNowrap, nil},
+		{WhiteSpacePre, []text.TextPos{4, 5, 8, 10}},
+		{WhiteSpacePreLine, []text.TextPos{4, 5, 8, 10}},
+		{WhiteSpacePreWrap, []text.TextPos{4, 5, 8, 10}},
+		{WhiteSpaceBreakSpaces, []text.TextPos{4, 5, 8, 10}},
+	}
+	for _, tt := range tests {
+		got := SegmentBreaks(src, ref, tt.ws)
+		if len(got) != len(tt.want) {
+			t.Errorf("white-space %d: expected breaks %v, got %v", tt.ws, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("white-space %d: expected breaks %v, got %v", tt.ws, tt.want, got)
+				break
+			}
+		}
+	}
+}
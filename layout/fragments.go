@@ -0,0 +1,198 @@
+package layout
+
+// Inline box fragments.
+//
+// An inline box (BoxInline) is broken into one fragment per line box it
+// appears on. Inline layouters report the content area of each inline box per
+// line (LineBox.Inlines); FlowLayout turns these into border-box fragments.
+// When breaking lines, layouters reserve the horizontal space of an inline
+// box's start and end sides as told by InlineContext.LineEdges.
+// With box-decoration-break:slice, the start-side padding, border and margin
+// apply only to the very first fragment of an element, and the end-side ones
+// only to its very last. This also holds for inline boxes split around
+// block-level boxes, whose pieces share the NodeID of their element.
+
+// InlineExtent is reported by an inline layouter for every inline box on a
+// line box.
+type InlineExtent struct {
+	Box     *LayoutNode // BoxInline
+	Content Rect        // content area on this line, relative to the line box frame
+	First   bool        // the box starts on this line
+	Last    bool        // the box ends on this line
+}
+
+// InlineFragment is the part of an inline box on one line box. Rects are
+// relative to the content box of the block container owning the line box.
+type InlineFragment struct {
+	Block   BoxID
+	Frame   Rect // border box
+	Content Rect // content area
+	Start   bool // start-side padding, border and margin apply
+	End     bool // end-side padding, border and margin apply
+}
+
+type InlineFragmentsByBox map[BoxID][]InlineFragment
+
+// LineEdges returns the horizontal space (margin, border and padding) an inline
+// box takes at its start and end side on a line. first and last tell whether
+// the box starts or ends on that line. Inline layouters use it to reserve the
+// space when breaking lines.
+func (c InlineContext) LineEdges(n *LayoutNode, first, last bool) (start, end float32) {
+	hasStart, hasEnd := c.edgeFlags(n, first, last)
+	u := c.Used[n.BoxID]
+	left := u.Margin.Left + u.Border.Left + u.Padding.Left
+	right := u.Padding.Right + u.Border.Right + u.Margin.Right
+	if startsRight(n) {
+		left, right = right, left
+	}
+	if hasStart {
+		start = left
+	}
+	if hasEnd {
+		end = right
+	}
+	return start, end
+}
+
+// startsRight tells whether the start side of an inline box is its line-right
+// side, i.e. whether its direction is rtl.
+func startsRight(n *LayoutNode) bool {
+	return n != nil && n.Style != nil && n.Style.Direction == DirectionRTL
+}
+
+func (c InlineContext) edgeFlags(n *LayoutNode, first, last bool) (start, end bool) {
+	if n.Style != nil && n.Style.BoxDecorationBreak == BoxDecorationClone {
+		return true, true
+	}
+	p := c.pieces[n.BoxID]
+	return first && !p.noStart, last && !p.noEnd
+}
+
+// pieceEdges marks the inner sides of the pieces of a split inline box.
+type pieceEdges struct {
+	noStart, noEnd bool
+}
+
+// inlineState collects inline fragments during flow layout. A block container
+// may be laid out more than once (atomic inlines are sized again during trial
+// breaking); the fragments of its last layout replace earlier ones.
+type inlineState struct {
+	frags   InlineFragmentsByBox
+	byBlock map[BoxID][]BoxID // inline boxes with fragments, by block container
+	pieces  map[BoxID]pieceEdges
+	indents map[BoxID]indentSource // text-indent of anonymous block boxes
+}
+
+func newInlineState(root *LayoutNode) *inlineState {
+	return &inlineState{
+		frags:   make(InlineFragmentsByBox),
+		byBlock: make(map[BoxID][]BoxID),
+		pieces:  splitInlinePieces(root),
+		indents: anonymousIndents(root),
+	}
+}
+
+func (s *inlineState) context(block *LayoutNode, used UsedValuesTable, contentWidth float32) InlineContext {
+	ctx := InlineContext{
+		Block:       block,
+		Used:        used,
+		WritingMode: used[block.BoxID].WritingMode,
+	}
+	style := block.Style
+	if s != nil {
+		ctx.pieces = s.pieces
+		if src, ok := s.indents[block.BoxID]; ok {
+			ctx.indent, style = src, src.style
+		}
+	}
+	if style != nil {
+		ctx.Direction = style.Direction
+	}
+	ctx.TextIndent = usedTextIndent(style, contentWidth)
+	return ctx
+}
+
+func (s *inlineState) collect(ctx InlineContext, lines []LineBox) {
+	if s == nil {
+		return
+	}
+	block := ctx.Block.BoxID
+	for _, id := range s.byBlock[block] {
+		delete(s.frags, id)
+	}
+	s.byBlock[block] = s.byBlock[block][:0]
+	for _, line := range lines {
+		for _, ext := range line.Inlines {
+			if ext.Box == nil {
+				continue
+			}
+			f := InlineFragment{Block: block, Content: ext.Content}
+			f.Content.X += line.Frame.X
+			f.Content.Y += line.Frame.Y
+			f.Start, f.End = ctx.edgeFlags(ext.Box, ext.First, ext.Last)
+			f.Frame = inlineFragmentFrame(f.Content, ctx.Used[ext.Box.BoxID], f.Start, f.End, startsRight(ext.Box))
+			if len(s.frags[ext.Box.BoxID]) == 0 {
+				s.byBlock[block] = append(s.byBlock[block], ext.Box.BoxID)
+			}
+			s.frags[ext.Box.BoxID] = append(s.frags[ext.Box.BoxID], f)
+		}
+	}
+}
+
+// splitInlinePieces finds inline boxes split into several pieces sharing
+// their element (and pseudo-element), and marks the inner sides of the pieces.
+func splitInlinePieces(root *LayoutNode) map[BoxID]pieceEdges {
+	type elementKey struct {
+		node   NodeID
+		pseudo PseudoElement
+	}
+	pieces := make(map[elementKey][]BoxID)
+	var walk func(n *LayoutNode)
+	walk = func(n *LayoutNode) {
+		if n == nil {
+			return
+		}
+		if n.Box == BoxInline {
+			key := elementKey{n.NodeID, n.Pseudo}
+			pieces[key] = append(pieces[key], n.BoxID)
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	edges := make(map[BoxID]pieceEdges)
+	for _, ids := range pieces {
+		if len(ids) < 2 {
+			continue
+		}
+		for i, id := range ids {
+			edges[id] = pieceEdges{noStart: i > 0, noEnd: i < len(ids)-1}
+		}
+	}
+	return edges
+}
+
+// inlineFragmentFrame returns the border box of an inline fragment. rtl maps
+// the start side to the right and the end side to the left.
+func inlineFragmentFrame(content Rect, u UsedValues, start, end, rtl bool) Rect {
+	left, right := start, end
+	if rtl {
+		left, right = end, start
+	}
+	frame := Rect{
+		X: content.X,
+		Y: content.Y - (u.Border.Top + u.Padding.Top),
+		W: content.W,
+		H: content.H + u.Border.Top + u.Padding.Top + u.Padding.Bottom + u.Border.Bottom,
+	}
+	if left {
+		frame.X -= u.Border.Left + u.Padding.Left
+		frame.W += u.Border.Left + u.Padding.Left
+	}
+	if right {
+		frame.W += u.Padding.Right + u.Border.Right
+	}
+	return frame
+}
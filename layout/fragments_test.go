@@ -0,0 +1,185 @@
+package layout
+
+import "testing"
+
+// fakeLinesByRoot returns preset line boxes per inline root.
+type fakeLinesByRoot map[BoxID][]LineBox
+
+func (f fakeLinesByRoot) LayoutInline(inlineRoot *LayoutNode, maxWidth float32, atomic AtomicSizer, ctx InlineContext) ([]LineBox, error) {
+	return f[inlineRoot.BoxID], nil
+}
+
+func TestFlowLayout_InlineFragments(t *testing.T) {
+	edges := Edges{Top: 1, Right: 1, Bottom: 1, Left: 1}
+	padding := Edges{Top: 2, Right: 2, Bottom: 2, Left: 2}
+	for _, tt := range []struct {
+		name       string
+		decoration BoxDecorationBreak
+		want       []Rect
+	}{
+		{name: "slice", decoration: BoxDecorationSlice, want: []Rect{{X: 7, Y: 0, W: 53, H: 16}, {X: 0, Y: 20, W: 33, H: 16}}},
+		{name: "clone", decoration: BoxDecorationClone, want: []Rect{{X: 7, Y: 0, W: 56, H: 16}, {X: -3, Y: 20, W: 36, H: 16}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			span := &LayoutNode{BoxID: 3, NodeID: 7, Box: BoxInline,
+				Style: &ComputedStyle{BoxDecorationBreak: tt.decoration}}
+			inlineRoot := &LayoutNode{BoxID: 2, Box: BoxAnonymousInline, Children: []*LayoutNode{span}}
+			root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{inlineRoot}}
+			used := UsedValuesTable{
+				root.BoxID: {ContentWidth: 100},
+				span.BoxID: {Border: edges, Padding: padding},
+			}
+			inline := fakeLinesByRoot{inlineRoot.BoxID: {
+				{Frame: Rect{W: 100, H: 20}, Inlines: []InlineExtent{
+					{Box: span, Content: Rect{X: 10, Y: 3, W: 50, H: 10}, First: true},
+				}},
+				{Frame: Rect{Y: 20, W: 100, H: 20}, Inlines: []InlineExtent{
+					{Box: span, Content: Rect{X: 0, Y: 3, W: 30, H: 10}, Last: true},
+				}},
+			}}
+
+			res, err := FlowLayout(root, used, inline, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+			if err != nil {
+				t.Fatalf("FlowLayout error: %v", err)
+			}
+			frags := res.Fragments[span.BoxID]
+			if len(frags) != 2 {
+				t.Fatalf("expected 2 fragments, got %d", len(frags))
+			}
+			for i, f := range frags {
+				if f.Block != root.BoxID {
+					t.Errorf("fragment %d: expected block %d, got %d", i, root.BoxID, f.Block)
+				}
+				if f.Frame != tt.want[i] {
+					t.Errorf("fragment %d: expected frame %+v, got %+v", i, tt.want[i], f.Frame)
+				}
+			}
+		})
+	}
+}
+
+func TestFlowLayout_InlineFragmentsOfSplitInline(t *testing.T) {
+	// <span>a<div/>b</span>: two BoxInline pieces sharing NodeID 7.
+	style := &ComputedStyle{}
+	piece1 := &LayoutNode{BoxID: 4, NodeID: 7, Box: BoxInline, Style: style}
+	piece2 := &LayoutNode{BoxID: 8, NodeID: 7, Box: BoxInline, Style: style}
+	anon1 := &LayoutNode{BoxID: 2, Box: BoxAnonymousBlock, Children: []*LayoutNode{
+		{BoxID: 3, Box: BoxAnonymousInline, Children: []*LayoutNode{piece1}},
+	}}
+	block := &LayoutNode{BoxID: 5, NodeID: 8, Box: BoxBlock}
+	anon2 := &LayoutNode{BoxID: 6, Box: BoxAnonymousBlock, Children: []*LayoutNode{
+		{BoxID: 7, Box: BoxAnonymousInline, Children: []*LayoutNode{piece2}},
+	}}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{anon1, block, anon2}}
+	used := UsedValuesTable{
+		root.BoxID:   {ContentWidth: 100},
+		anon1.BoxID:  {ContentWidth: 100},
+		block.BoxID:  {ContentWidth: 100},
+		anon2.BoxID:  {ContentWidth: 100},
+		piece1.BoxID: {Padding: Edges{Left: 4, Right: 4}},
+		piece2.BoxID: {Padding: Edges{Left: 4, Right: 4}},
+	}
+	line := func(n *LayoutNode) []LineBox {
+		return []LineBox{{Frame: Rect{W: 100, H: 20}, Inlines: []InlineExtent{
+			{Box: n, Content: Rect{X: 4, W: 10, H: 20}, First: true, Last: true},
+		}}}
+	}
+	inline := fakeLinesByRoot{3: line(piece1), 7: line(piece2)}
+
+	res, err := FlowLayout(root, used, inline, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	f1, f2 := res.Fragments[piece1.BoxID], res.Fragments[piece2.BoxID]
+	if len(f1) != 1 || len(f2) != 1 {
+		t.Fatalf("expected one fragment per piece, got %d and %d", len(f1), len(f2))
+	}
+	if !f1[0].Start || f1[0].End || f1[0].Frame != (Rect{X: 0, W: 14, H: 20}) {
+		t.Errorf("unexpected first piece fragment %+v", f1[0])
+	}
+	if f2[0].Start || !f2[0].End || f2[0].Frame != (Rect{X: 4, W: 14, H: 20}) {
+		t.Errorf("unexpected last piece fragment %+v", f2[0])
+	}
+	if f2[0].Block != anon2.BoxID {
+		t.Errorf("expected fragment to belong to block %d, got %d", anon2.BoxID, f2[0].Block)
+	}
+}
+
+func TestAtomicSizer_FragmentsReplacedOnRelayout(t *testing.T) {
+	span := &LayoutNode{BoxID: 3, NodeID: 7, Box: BoxInline}
+	inlineRoot := &LayoutNode{BoxID: 2, Box: BoxAnonymousInline, Children: []*LayoutNode{span}}
+	inlineBlock := &LayoutNode{BoxID: 1, Box: BoxInlineBlock, Children: []*LayoutNode{inlineRoot}}
+	inline := fakeLinesByRoot{inlineRoot.BoxID: {
+		{Frame: Rect{W: 50, H: 20}, Inlines: []InlineExtent{
+			{Box: span, Content: Rect{W: 20, H: 20}, First: true},
+		}},
+		{Frame: Rect{Y: 20, W: 50, H: 20}, Inlines: []InlineExtent{
+			{Box: span, Content: Rect{W: 10, H: 20}, Last: true},
+		}},
+	}}
+	inlines := newInlineState(inlineBlock)
+	a := atomicSizer{
+		inline:    inline,
+		intrinsic: fakeIntrinsic{},
+		used:      UsedValuesTable{inlineBlock.BoxID: {ContentWidth: 50}},
+		geom:      make(LayoutGeometryTable),
+		lines:     make(LinesByBlock),
+		inlines:   inlines,
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := a.SizeAtomicInline(inlineBlock, 100); err != nil {
+			t.Fatalf("SizeAtomicInline error: %v", err)
+		}
+	}
+	if got := len(inlines.frags[span.BoxID]); got != 2 {
+		t.Fatalf("expected 2 fragments after sizing twice, got %d", got)
+	}
+}
+
+func TestInlineContext_LineEdges(t *testing.T) {
+	used := UsedValuesTable{
+		3: {Margin: Edges{Left: 1, Right: 2}, Border: Edges{Left: 3, Right: 4}, Padding: Edges{Left: 5, Right: 6}},
+	}
+	slice := &LayoutNode{BoxID: 3, Box: BoxInline}
+	clone := &LayoutNode{BoxID: 3, Box: BoxInline, Style: &ComputedStyle{BoxDecorationBreak: BoxDecorationClone}}
+	rtl := &LayoutNode{BoxID: 3, Box: BoxInline, Style: &ComputedStyle{Direction: DirectionRTL}}
+	ctx := InlineContext{Used: used}
+	split := InlineContext{Used: used, pieces: map[BoxID]pieceEdges{3: {noStart: true}}}
+
+	tests := []struct {
+		name        string
+		ctx         InlineContext
+		node        *LayoutNode
+		first, last bool
+		start, end  float32
+	}{
+		{name: "whole_line", ctx: ctx, node: slice, first: true, last: true, start: 9, end: 12},
+		{name: "first_line", ctx: ctx, node: slice, first: true, start: 9},
+		{name: "middle_line", ctx: ctx, node: slice},
+		{name: "last_line", ctx: ctx, node: slice, last: true, end: 12},
+		{name: "clone", ctx: ctx, node: clone, start: 9, end: 12},
+		{name: "split_piece", ctx: split, node: slice, first: true, last: true, end: 12},
+		{name: "rtl_first_line", ctx: ctx, node: rtl, first: true, start: 12},
+		{name: "rtl_last_line", ctx: ctx, node: rtl, last: true, end: 9},
+	}
+	for _, tt := range tests {
+		start, end := tt.ctx.LineEdges(tt.node, tt.first, tt.last)
+		if start != tt.start || end != tt.end {
+			t.Errorf("%s: expected edges %v/%v, got %v/%v", tt.name, tt.start, tt.end, start, end)
+		}
+	}
+}
+
+func TestInlineFragmentFrame_RTL(t *testing.T) {
+	u := UsedValues{Border: Edges{Left: 1, Right: 1}, Padding: Edges{Left: 2, Right: 2}}
+	content := Rect{X: 10, W: 50, H: 10}
+	if f := inlineFragmentFrame(content, u, true, false, false); f.X != 7 || f.W != 53 {
+		t.Errorf("ltr start fragment: expected edges on the left, got %+v", f)
+	}
+	if f := inlineFragmentFrame(content, u, true, false, true); f.X != 10 || f.W != 53 {
+		t.Errorf("rtl start fragment: expected edges on the right, got %+v", f)
+	}
+	if f := inlineFragmentFrame(content, u, false, true, true); f.X != 7 || f.W != 53 {
+		t.Errorf("rtl end fragment: expected edges on the left, got %+v", f)
+	}
+}
@@ -3,6 +3,12 @@ package layout
 type LayoutGeometry struct {
 	Frame   Rect
 	Content Rect
+	Object  Rect // replaced elements only: rect of the replaced content after object-fit/object-position
+
+	// Baseline of the last line box in normal flow, measured from the top of the
+	// border box. Valid if HasBaseline.
+	Baseline    float32
+	HasBaseline bool
 }
 
 type LayoutGeometryTable map[BoxID]LayoutGeometry
@@ -0,0 +1,59 @@
+package layout
+
+// Initial letters (CSS Inline 3 §7). initial-letter on a ::first-letter box
+// makes a drop, sunken or raised cap: the letter is scaled so that it spans
+// Size lines, from the cap height of the first line to the baseline of line
+// Size, and is then lowered to the baseline of line Sink. The inline layouter
+// places it at the start of the first line, leaves it out of the line height
+// calculation and shortens the first InitialLetterBox.Lines line boxes by its
+// advance, so that the following text wraps around it.
+
+// InitialLetter is the computed value of initial-letter. A zero Size means
+// "normal".
+type InitialLetter struct {
+	Size float32 // number of lines the letter spans
+	Sink int     // line whose baseline the letter sits on; 0 means the default
+}
+
+// UsedSink returns the sink of l: Sink if given, else Size rounded down to
+// at least 1.
+func (l InitialLetter) UsedSink() int {
+	if l.Sink > 0 {
+		return l.Sink
+	}
+	return max(1, int(l.Size))
+}
+
+// InitialLetterBox is the used size and position of an initial letter.
+type InitialLetterBox struct {
+	FontSize float32 // used font size of the letter
+	Drop     float32 // distance of the letter's baseline below the first line's baseline
+	Lines    int     // number of line boxes the letter intrudes into: the sink
+}
+
+// Cap height as a fraction of the font size, for fonts which do not record it.
+const fallbackCapHeight = 0.7
+
+// InitialLetterSize sizes an initial letter. block and blockFontSize are the
+// first available font of the block container and its size, lineHeight its
+// used line-height. letter and letterFontSize are the letter's font metrics at
+// its computed font size. ok is false for initial-letter "normal".
+func InitialLetterSize(l InitialLetter, block FontMetrics, blockFontSize, lineHeight float32, letter FontMetrics, letterFontSize float32) (box InitialLetterBox, ok bool) {
+	if l.Size <= 0 || letterFontSize <= 0 {
+		return InitialLetterBox{}, false
+	}
+	capHeight := (l.Size-1)*lineHeight + capHeightOf(block, blockFontSize)
+	sink := l.UsedSink()
+	return InitialLetterBox{
+		FontSize: capHeight / (capHeightOf(letter, letterFontSize) / letterFontSize),
+		Drop:     float32(sink-1) * lineHeight,
+		Lines:    sink,
+	}, true
+}
+
+func capHeightOf(m FontMetrics, fontSize float32) float32 {
+	if m.CapHeight > 0 {
+		return m.CapHeight
+	}
+	return fontSize * fallbackCapHeight
+}
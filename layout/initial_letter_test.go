@@ -0,0 +1,33 @@
+package layout
+
+import "testing"
+
+func TestInitialLetterSize(t *testing.T) {
+	block := FontMetrics{Ascent: 16, Descent: 4, CapHeight: 10}
+	letter := FontMetrics{Ascent: 16, Descent: 4, CapHeight: 16} // at 20px
+	tests := []struct {
+		name   string
+		l      InitialLetter
+		letter FontMetrics
+		want   InitialLetterBox
+	}{
+		// The letter spans from the first line's cap height to the third baseline: 2·20 + 10.
+		{"drop", InitialLetter{Size: 3}, letter, InitialLetterBox{FontSize: 62.5, Drop: 40, Lines: 3}},
+		{"raised", InitialLetter{Size: 3, Sink: 1}, letter, InitialLetterBox{FontSize: 62.5, Drop: 0, Lines: 1}},
+		{"sunken", InitialLetter{Size: 2, Sink: 3}, letter, InitialLetterBox{FontSize: 37.5, Drop: 40, Lines: 3}},
+		{"fractional", InitialLetter{Size: 2.5}, letter, InitialLetterBox{FontSize: 50, Drop: 20, Lines: 2}},
+		// Without a cap height the letter's is taken as 0.7 of its font size.
+		{"no_cap_height", InitialLetter{Size: 2}, FontMetrics{}, InitialLetterBox{FontSize: 30 / 0.7, Drop: 20, Lines: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := InitialLetterSize(tt.l, block, 16, 20, tt.letter, 20)
+			if !ok || got != tt.want {
+				t.Fatalf("expected %+v, got %+v (ok %v)", tt.want, got, ok)
+			}
+		})
+	}
+	if _, ok := InitialLetterSize(InitialLetter{}, block, 16, 20, letter, 20); ok {
+		t.Fatalf("expected no initial letter for initial-letter normal")
+	}
+}
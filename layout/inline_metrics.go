@@ -0,0 +1,205 @@
+package layout
+
+// Vertical metrics of inline-level boxes (CSS 2.1 §10.8).
+//
+// Inline layouters place inline-level boxes relative to the baseline of their
+// parent inline box. The helpers in this file compute the vertical extent of
+// a box above and below its own baseline, and how far vertical-align shifts
+// that baseline.
+
+// FontMetrics are the vertical metrics of the first available font of an
+// inline box, in px.
+type FontMetrics struct {
+	Ascent    float32
+	Descent   float32 // positive, below the baseline
+	LineGap   float32
+	XHeight   float32
+	CapHeight float32
+}
+
+// InlineBoxMetrics describes the vertical extent of an inline-level box for
+// line box height computation.
+type InlineBoxMetrics struct {
+	Ascent  float32           // layout bounds above the box's baseline
+	Descent float32           // layout bounds below the box's baseline
+	Shift   float32           // baseline shift relative to the parent's baseline; positive raises the box
+	Align   VerticalAlignKind // VAlignTop/VAlignBottom boxes are aligned to the line box instead
+}
+
+// LineHeight is the computed value of line-height.
+type LineHeight struct {
+	Kind   LineHeightKind
+	Number float32 // LineHeightNumber: multiple of the box's font size
+	Length Length  // LineHeightLength; percentages refer to the box's font size
+}
+
+type LineHeightKind uint8
+
+const (
+	LineHeightNormal LineHeightKind = iota
+	LineHeightNumber
+	LineHeightLength
+)
+
+// Factor of the font size used for line-height:normal if the font reports no metrics.
+const normalLineHeight = 1.2
+
+// Used returns the used line-height of a box with the given font size and
+// first available font.
+func (lh LineHeight) Used(fontSize float32, font FontMetrics) float32 {
+	switch lh.Kind {
+	case LineHeightNumber:
+		return lh.Number * fontSize
+	case LineHeightLength:
+		switch lh.Length.Kind {
+		case LenPx:
+			return lh.Length.Value
+		case LenPercent, LenEm:
+			return lh.Length.Value * fontSize
+		}
+	}
+	if h := font.Ascent + font.Descent + font.LineGap; h > 0 {
+		return h
+	}
+	return fontSize * normalLineHeight
+}
+
+// InlineBoxBounds returns the layout bounds above and below the baseline of a
+// non-replaced inline box: its content area (the font's ascent and descent)
+// plus half the leading line-height - (A+D) on each side. The leading may be
+// negative.
+func InlineBoxBounds(font FontMetrics, lineHeight float32) (above, below float32) {
+	halfLeading := (lineHeight - (font.Ascent + font.Descent)) / 2
+	return font.Ascent + halfLeading, font.Descent + halfLeading
+}
+
+// StrutMetrics returns the metrics of the strut of a block container: an
+// imaginary zero-width inline box with the block's font and line-height,
+// starting each line box.
+func StrutMetrics(block *ComputedStyle, font FontMetrics) InlineBoxMetrics {
+	style := styleOrDefault(block)
+	above, below := InlineBoxBounds(font, style.LineHeight.Used(style.FontSizePx, font))
+	return InlineBoxMetrics{Ascent: above, Descent: below}
+}
+
+// NonReplacedInlineMetrics returns the metrics of a non-replaced inline box
+// (e.g. a span) with the given style and first available font, placed inside
+// a parent inline box with font metrics parent.
+func NonReplacedInlineMetrics(style *ComputedStyle, font FontMetrics, parent FontMetrics, parentFontSize float32) InlineBoxMetrics {
+	s := styleOrDefault(style)
+	lineHeight := s.LineHeight.Used(s.FontSizePx, font)
+	above, below := InlineBoxBounds(font, lineHeight)
+	return InlineBoxMetrics{
+		Ascent:  above,
+		Descent: below,
+		Shift:   BaselineShift(s.VerticalAlign, parent, parentFontSize, above, below, lineHeight, s.FontSizePx),
+		Align:   s.VerticalAlign.Kind,
+	}
+}
+
+// Approximate sub/super baseline shifts (fractions of the parent's font size),
+// used in the absence of font-provided values.
+const (
+	subscriptShift   = 0.2
+	superscriptShift = 0.34
+)
+
+// BaselineShift returns how far vertical-align raises a box with the given
+// layout bounds above its parent's baseline. lineHeight and fontSize are the
+// box's own used line-height and font size (for percentages and em lengths).
+func BaselineShift(va VerticalAlign, parent FontMetrics, parentFontSize, ascent, descent, lineHeight, fontSize float32) float32 {
+	switch va.Kind {
+	case VAlignSub:
+		return -parentFontSize * subscriptShift
+	case VAlignSuper:
+		return parentFontSize * superscriptShift
+	case VAlignTextTop:
+		return parent.Ascent - ascent
+	case VAlignTextBottom:
+		return descent - parent.Descent
+	case VAlignMiddle:
+		// Align the box's vertical midpoint with the parent's baseline plus half its x-height.
+		return parent.XHeight/2 - (ascent-descent)/2
+	case VAlignLength:
+		switch va.Shift.Kind {
+		case LenPercent:
+			return lineHeight * va.Shift.Value
+		case LenEm:
+			return fontSize * va.Shift.Value
+		case LenPx:
+			return va.Shift.Value
+		}
+	}
+	return 0
+}
+
+// InlineMetrics returns the metrics of an atomic inline placed inside a parent
+// inline box with font metrics parent. lineHeight is the atomic inline's own
+// line-height, the basis for percentage vertical-align values.
+func (b AtomicBox) InlineMetrics(parent FontMetrics, parentFontSize, lineHeight, fontSize float32) InlineBoxMetrics {
+	m := InlineBoxMetrics{
+		Ascent:  b.Baseline,
+		Descent: b.Height - b.Baseline,
+		Align:   b.Align.Kind,
+	}
+	m.Shift = BaselineShift(b.Align, parent, parentFontSize, m.Ascent, m.Descent, lineHeight, fontSize)
+	return m
+}
+
+// LineBoxMetrics computes the height of a line box from the inline-level boxes
+// on it (CSS 2.1 §10.8.1). Shifts of added boxes must be relative to the
+// baseline of the root inline box, i.e. callers add up the shifts of nested
+// inline boxes. Boxes nested in a top- or bottom-aligned box are expected to be
+// folded into that box's metrics.
+type LineBoxMetrics struct {
+	above, below float32            // extent of baseline-aligned boxes around the root baseline
+	aligned      []InlineBoxMetrics // boxes aligned to the top or bottom of the line box
+}
+
+// NewLineBoxMetrics starts a line box with the strut of its block container.
+func NewLineBoxMetrics(strut InlineBoxMetrics) *LineBoxMetrics {
+	l := &LineBoxMetrics{}
+	l.Add(strut)
+	return l
+}
+
+// Add includes an inline-level box in the line box.
+func (l *LineBoxMetrics) Add(m InlineBoxMetrics) {
+	if m.Align == VAlignTop || m.Align == VAlignBottom {
+		l.aligned = append(l.aligned, m)
+		return
+	}
+	l.above = max(l.above, m.Ascent+m.Shift)
+	l.below = max(l.below, m.Descent-m.Shift)
+}
+
+// Height returns the height of the line box and the distance from its top to
+// the baseline of the root inline box. Top- and bottom-aligned boxes taller
+// than the line extend it downwards and upwards, respectively.
+func (l *LineBoxMetrics) Height() (height, baseline float32) {
+	above, below := l.above, l.below
+	for _, m := range l.aligned {
+		if extra := m.Ascent + m.Descent - (above + below); extra > 0 {
+			if m.Align == VAlignTop {
+				below += extra
+			} else {
+				above += extra
+			}
+		}
+	}
+	return above + below, above
+}
+
+// BoxTop returns the distance from the top of the line box to the top of the
+// layout bounds of an inline-level box added to it.
+func (l *LineBoxMetrics) BoxTop(m InlineBoxMetrics) float32 {
+	height, baseline := l.Height()
+	switch m.Align {
+	case VAlignTop:
+		return 0
+	case VAlignBottom:
+		return height - (m.Ascent + m.Descent)
+	default:
+		return baseline - m.Shift - m.Ascent
+	}
+}
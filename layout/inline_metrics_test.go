@@ -0,0 +1,86 @@
+package layout
+
+import "testing"
+
+func TestBaselineShift(t *testing.T) {
+	parent := FontMetrics{Ascent: 12, Descent: 4, XHeight: 8}
+	tests := []struct {
+		name string
+		va   VerticalAlign
+		want float32
+	}{
+		{name: "baseline", va: VerticalAlign{Kind: VAlignBaseline}, want: 0},
+		{name: "text_top", va: VerticalAlign{Kind: VAlignTextTop}, want: 12 - 20},
+		{name: "text_bottom", va: VerticalAlign{Kind: VAlignTextBottom}, want: 10 - 4},
+		{name: "middle", va: VerticalAlign{Kind: VAlignMiddle}, want: 4 - 5},
+		{name: "length_px", va: VerticalAlign{Kind: VAlignLength, Shift: lenPx(3)}, want: 3},
+		{name: "length_percent", va: VerticalAlign{Kind: VAlignLength, Shift: lenPct(-0.5)}, want: -12},
+		{name: "top", va: VerticalAlign{Kind: VAlignTop}, want: 0},
+	}
+	for _, tt := range tests {
+		got := BaselineShift(tt.va, parent, 16, 20, 10, 24, 16)
+		if got != tt.want {
+			t.Errorf("%s: shift = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLineHeightUsed(t *testing.T) {
+	font := FontMetrics{Ascent: 14, Descent: 4, LineGap: 2}
+	tests := []struct {
+		name string
+		lh   LineHeight
+		font FontMetrics
+		want float32
+	}{
+		{name: "normal", font: font, want: 20},
+		{name: "normal_without_metrics", want: 19.2},
+		{name: "number", lh: LineHeight{Kind: LineHeightNumber, Number: 1.5}, want: 24},
+		{name: "px", lh: LineHeight{Kind: LineHeightLength, Length: lenPx(30)}, want: 30},
+		{name: "percent", lh: LineHeight{Kind: LineHeightLength, Length: lenPct(2)}, want: 32},
+	}
+	for _, tt := range tests {
+		if got := tt.lh.Used(16, tt.font); got != tt.want {
+			t.Errorf("%s: line-height = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLineBoxMetrics(t *testing.T) {
+	font := FontMetrics{Ascent: 12, Descent: 4, XHeight: 8}
+	block := &ComputedStyle{FontSizePx: 16, LineHeight: LineHeight{Kind: LineHeightLength, Length: lenPx(20)}}
+	strut := StrutMetrics(block, font)
+	if strut.Ascent != 14 || strut.Descent != 6 {
+		t.Fatalf("expected strut bounds 14/6, got %v/%v", strut.Ascent, strut.Descent)
+	}
+
+	line := NewLineBoxMetrics(strut)
+	if h, b := line.Height(); h != 20 || b != 14 {
+		t.Fatalf("expected strut-only line 20/14, got %v/%v", h, b)
+	}
+
+	// A span with line-height 10 (bounds 9/1), raised by 8px.
+	raised := NonReplacedInlineMetrics(&ComputedStyle{
+		FontSizePx:    16,
+		LineHeight:    LineHeight{Kind: LineHeightLength, Length: lenPx(10)},
+		VerticalAlign: VerticalAlign{Kind: VAlignLength, Shift: lenPx(8)},
+	}, font, font, 16)
+	line.Add(raised)
+	h, b := line.Height()
+	if h != 23 || b != 17 {
+		t.Fatalf("expected line 23/17, got %v/%v", h, b)
+	}
+
+	// A tall top-aligned box extends the line below the baseline.
+	top := InlineBoxMetrics{Ascent: 30, Descent: 10, Align: VAlignTop}
+	line.Add(top)
+	if h2, b2 := line.Height(); h2 != 40 || b2 != b {
+		t.Fatalf("expected line 40/%v, got %v/%v", b, h2, b2)
+	}
+	if y := line.BoxTop(top); y != 0 {
+		t.Fatalf("expected top-aligned box at 0, got %v", y)
+	}
+	if y := line.BoxTop(strut); y != b-14 {
+		t.Fatalf("expected strut at %v, got %v", b-14, y)
+	}
+}
@@ -12,22 +12,99 @@ type StyNodeView interface {
 	ComputedStyle(string) string
 }
 
+// InlineLayouter breaks inline content into line boxes. Line box heights
+// follow CSS 2.1 §10.8: each line starts with the strut of the block container
+// (see StrutMetrics and LineBoxMetrics). Lines end after forced line breaks,
+// i.e. BoxLineBreak leaves and preserved segment breaks (see SegmentBreaks).
 type InlineLayouter interface {
 	LayoutInline(
 		inlineRoot *LayoutNode, // BoxAnonymousInline
 		maxWidth float32,
 		atomic AtomicSizer, // callback for atomic inline items (inline-block)
+		ctx InlineContext,
 	) ([]LineBox, error)
 }
 
+// InlineContext describes the inline formatting context an inline layouter
+// works in.
+type InlineContext struct {
+	Block *LayoutNode     // block container owning the line boxes; its style defines the strut
+	Used  UsedValuesTable // used values of the boxes in the inline formatting context
+
+	// WritingMode is the writing mode of Block. maxWidth and the line boxes
+	// are logical in it: in vertical writing modes, the available "width" is
+	// physically a height.
+	WritingMode WritingMode
+
+	// Direction is the inline base direction of Block. Anonymous blocks take
+	// it, like their text-indent, from the element they belong to.
+	Direction Direction
+
+	// TextIndent is the used text-indent of Block; see LineIndent.
+	TextIndent float32
+
+	pieces map[BoxID]pieceEdges // pieces of inline boxes split around block-level boxes
+	indent indentSource         // text-indent of an anonymous Block
+}
+
+// AtomicSizer lays out atomic inlines for an inline layouter. maxWidth is the
+// available width on the line; returned sizes are margin-box sizes. The geometry
+// recorded for the atomic inline is relative to its margin-box origin.
 type AtomicSizer interface {
 	SizeInlineBlock(node *LayoutNode, maxWidth float32) (w, h float32, err error)
+	// SizeAtomicInline sizes an atomic inline (inline-block or replaced element)
+	// and additionally reports its baseline and vertical alignment.
+	SizeAtomicInline(node *LayoutNode, maxWidth float32) (AtomicBox, error)
+}
+
+// AtomicBox is the result of sizing an atomic inline. Sizes refer to the
+// margin box, which is what occupies space on a line.
+type AtomicBox struct {
+	Width, Height float32       // margin box
+	Baseline      float32       // distance from the top of the margin box to the baseline
+	Align         VerticalAlign // computed vertical-align of the atomic inline
 }
 
+// IntrinsicMeasurer measures the intrinsic widths of a box's content, i.e.
+// excluding the box's own padding, border and margins.
 type IntrinsicMeasurer interface {
 	MaxContentWidth(node *LayoutNode) (float32, error)
+	MinContentWidth(node *LayoutNode) (float32, error)
 }
 
+// InlineIntrinsic measures the intrinsic widths of inline content rooted at a
+// BoxAnonymousInline: the max-content width is the width of the content laid
+// out without any soft wraps, the min-content width is the width of its widest
+// unbreakable unit. Atomic inlines and the start/end edges of inline boxes are
+// measured through the given IntrinsicContributor.
 type InlineIntrinsic interface {
-	MaxContentWidth(inlineRoot *LayoutNode) (float32, error)
+	MaxContentWidth(inlineRoot *LayoutNode, c IntrinsicContributor) (float32, error)
+	MinContentWidth(inlineRoot *LayoutNode, c IntrinsicContributor) (float32, error)
+}
+
+// IntrinsicContributor reports the intrinsic contributions of boxes nested in
+// inline content to an InlineIntrinsic.
+type IntrinsicContributor interface {
+	// OuterMaxContentWidth and OuterMinContentWidth return the intrinsic
+	// widths of an atomic inline's margin box.
+	OuterMaxContentWidth(node *LayoutNode) (float32, error)
+	OuterMinContentWidth(node *LayoutNode) (float32, error)
+	// InlineEdges returns the sums of margin, border and padding at the start
+	// and end side of an inline box.
+	InlineEdges(node *LayoutNode) (start, end float32)
+}
+
+// ReplacedMeasurer reports the natural dimensions of replaced content (images,
+// video, canvas, …). The IntrinsicMeasurer passed to FlowLayout may implement
+// it; otherwise replaced elements are treated as having no natural dimensions.
+type ReplacedMeasurer interface {
+	NaturalSize(node *LayoutNode) (NaturalSize, error)
+}
+
+// NaturalSize holds the natural width, height and aspect ratio of replaced
+// content. Any of them may be missing.
+type NaturalSize struct {
+	Width, Height       float32
+	HasWidth, HasHeight bool
+	Ratio               float32 // width / height; 0 if there is no natural ratio
 }
@@ -0,0 +1,225 @@
+package layout
+
+// Default intrinsic measurement.
+//
+// The intrinsic widths of a block container with block-level children are the
+// largest outer contributions of its children; those of an inline formatting
+// context are delegated to an InlineIntrinsic, which calls back into the
+// measurer for atomic inlines and inline box edges. A box with a definite
+// width contributes that width; percentages count as auto, as they cannot be
+// resolved against an intrinsically sized containing block. Sizes are logical
+// in the writing mode of the measured box.
+
+type intrinsicMeasurer struct {
+	inline   InlineIntrinsic
+	used     UsedValuesTable
+	min, max map[BoxID]float32 // cached content-box widths
+}
+
+// NewIntrinsicMeasurer returns an IntrinsicMeasurer which computes min- and
+// max-content widths recursively from a box's children, measuring inline
+// content with inline. used must be the used-values table passed to FlowLayout.
+//
+// If inline implements ReplacedMeasurer, the measurer reports natural sizes of
+// replaced elements through it.
+func NewIntrinsicMeasurer(inline InlineIntrinsic, used UsedValuesTable) IntrinsicMeasurer {
+	return &intrinsicMeasurer{
+		inline: inline,
+		used:   used,
+		min:    make(map[BoxID]float32),
+		max:    make(map[BoxID]float32),
+	}
+}
+
+func (m *intrinsicMeasurer) MaxContentWidth(node *LayoutNode) (float32, error) {
+	return m.measure(node, false)
+}
+
+func (m *intrinsicMeasurer) MinContentWidth(node *LayoutNode) (float32, error) {
+	return m.measure(node, true)
+}
+
+func (m *intrinsicMeasurer) OuterMaxContentWidth(node *LayoutNode) (float32, error) {
+	return m.outer(node, false)
+}
+
+func (m *intrinsicMeasurer) OuterMinContentWidth(node *LayoutNode) (float32, error) {
+	return m.outer(node, true)
+}
+
+func (m *intrinsicMeasurer) InlineEdges(node *LayoutNode) (start, end float32) {
+	u := m.used[node.BoxID]
+	start = u.Margin.Left + u.Border.Left + u.Padding.Left
+	end = u.Margin.Right + u.Border.Right + u.Padding.Right
+	if startsRight(node) {
+		start, end = end, start
+	}
+	return start, end
+}
+
+func (m *intrinsicMeasurer) NaturalSize(node *LayoutNode) (NaturalSize, error) {
+	if rm, ok := m.inline.(ReplacedMeasurer); ok {
+		return rm.NaturalSize(node)
+	}
+	return NaturalSize{}, nil
+}
+
+func (m *intrinsicMeasurer) measure(node *LayoutNode, minContent bool) (float32, error) {
+	if node == nil {
+		return 0, nil
+	}
+	cache := m.max
+	if minContent {
+		cache = m.min
+	}
+	if w, ok := cache[node.BoxID]; ok {
+		return w, nil
+	}
+	w, err := m.measureContent(node, minContent)
+	if err != nil {
+		return 0, err
+	}
+	cache[node.BoxID] = w
+	return w, nil
+}
+
+func (m *intrinsicMeasurer) measureContent(node *LayoutNode, minContent bool) (float32, error) {
+	switch {
+	case node.Box == BoxReplaced:
+		return m.replacedWidth(node)
+	case isInlineOnlyBlockContainer(node):
+		return m.measureInline(node.Children[0], minContent)
+	case node.Box == BoxAnonymousInline:
+		return m.measureInline(node, minContent)
+	}
+	var w float32
+	mode := m.used[node.BoxID].WritingMode
+	for _, child := range node.Children {
+		if child == nil {
+			continue
+		}
+		if orthogonal(m.used[child.BoxID].WritingMode, mode) {
+			w = max(w, m.orthogonalOuter(child))
+			continue
+		}
+		cw, err := m.outer(child, minContent)
+		if err != nil {
+			return 0, err
+		}
+		w = max(w, cw)
+	}
+	return w, nil
+}
+
+func (m *intrinsicMeasurer) measureInline(inlineRoot *LayoutNode, minContent bool) (float32, error) {
+	if m.inline == nil {
+		return 0, errNotImplemented
+	}
+	if minContent {
+		return m.inline.MinContentWidth(inlineRoot, m)
+	}
+	return m.inline.MaxContentWidth(inlineRoot, m)
+}
+
+// outer returns the intrinsic contribution of node's margin box.
+func (m *intrinsicMeasurer) outer(node *LayoutNode, minContent bool) (float32, error) {
+	u := m.used[node.BoxID]
+	w := u.ContentWidth
+	if !hasDefiniteWidth(node, u) {
+		if iw := u.IntrinsicWidth; iw != nil && iw.Kind != LenFitContent {
+			// min-content and max-content boxes contribute that size in both cases.
+			minContent = iw.Kind == LenMinContent
+		}
+		cw, err := m.measure(node, minContent)
+		if err != nil {
+			return 0, err
+		}
+		w = u.Limits.clampWidth(cw)
+	}
+	return w + u.Margin.Left + u.Margin.Right + u.Border.Left + u.Border.Right +
+		u.Padding.Left + u.Padding.Right, nil
+}
+
+// orthogonalOuter returns the contribution of a child in an orthogonal flow:
+// its outer block size. Without laying it out, an auto block size counts as 0.
+func (m *intrinsicMeasurer) orthogonalOuter(node *LayoutNode) float32 {
+	u := m.used[node.BoxID]
+	var h float32
+	if u.HasHeight {
+		h = u.Limits.clampHeight(u.ContentHeight)
+	}
+	return h + u.Margin.Top + u.Margin.Bottom + u.Border.Top + u.Border.Bottom +
+		u.Padding.Top + u.Padding.Bottom
+}
+
+// replacedWidth is the width of a replaced element sized without an available
+// width; min- and max-content widths coincide.
+func (m *intrinsicMeasurer) replacedWidth(node *LayoutNode) (float32, error) {
+	natural, err := naturalSize(m, node)
+	if err != nil {
+		return 0, err
+	}
+	u := m.used[node.BoxID]
+	natural = logicalNatural(natural, u.WritingMode)
+	w, _ := replacedContentSize(u, false, replacedSizing(usedStyle(node, u), natural), 0)
+	return w, nil
+}
+
+// hasDefiniteWidth tells whether node's used content width is known without
+// measuring its contents.
+func hasDefiniteWidth(node *LayoutNode, u UsedValues) bool {
+	if node.Style == nil {
+		return false
+	}
+	switch usedStyle(node, u).Width.Kind {
+	case LenPx, LenEm:
+		return true
+	case LenAuto:
+		return u.AspectRatio > 0 && u.HasHeight
+	default:
+		return false
+	}
+}
+
+// resolveIntrinsicWidth computes the used content width of node from its
+// intrinsic size keyword, with avail the available content width, records it
+// in used and re-resolves node's children against it.
+func resolveIntrinsicWidth(node *LayoutNode, avail float32, used UsedValuesTable, intrinsic IntrinsicMeasurer) (float32, error) {
+	u := used[node.BoxID]
+	if intrinsic == nil {
+		return 0, errNotImplemented
+	}
+	var w float32
+	var err error
+	switch u.IntrinsicWidth.Kind {
+	case LenMinContent:
+		w, err = intrinsic.MinContentWidth(node)
+	case LenMaxContent:
+		w, err = intrinsic.MaxContentWidth(node)
+	default:
+		var minContent, maxContent float32
+		if minContent, err = intrinsic.MinContentWidth(node); err != nil {
+			return 0, err
+		}
+		if maxContent, err = intrinsic.MaxContentWidth(node); err != nil {
+			return 0, err
+		}
+		if u.IntrinsicWidth.Limit > 0 {
+			avail = u.IntrinsicWidth.Limit
+		}
+		w = shrinkToFit(minContent, maxContent, avail)
+	}
+	if err != nil {
+		return 0, err
+	}
+	w = u.Limits.clampWidth(max(w, 0))
+	u.ContentWidth = w
+	used[node.BoxID] = u
+
+	ctx := u.IntrinsicWidth.childCtx
+	ctx.ContainingBlock.W = w
+	for _, child := range node.Children {
+		resolveUsedValues(child, ctx, used)
+	}
+	return w, nil
+}
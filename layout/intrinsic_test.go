@@ -0,0 +1,211 @@
+package layout
+
+import "testing"
+
+// fakeInlineIntrinsic treats every word of a text box as an unbreakable unit.
+// Inline box edges only count towards the max-content width. As an inline
+// layouter, it puts all atomic inlines on a single line.
+type fakeInlineIntrinsic struct {
+	words map[BoxID][]float32
+}
+
+func (f fakeInlineIntrinsic) LayoutInline(inlineRoot *LayoutNode, maxWidth float32, atomic AtomicSizer, ctx InlineContext) ([]LineBox, error) {
+	var line LineBox
+	for _, child := range inlineRoot.Children {
+		if child.Box != BoxInlineBlock && child.Box != BoxReplaced {
+			continue
+		}
+		box, err := atomic.SizeAtomicInline(child, maxWidth-line.Frame.W)
+		if err != nil {
+			return nil, err
+		}
+		line.Frame.W += box.Width
+		line.Frame.H = max(line.Frame.H, box.Height)
+	}
+	return []LineBox{line}, nil
+}
+
+func (f fakeInlineIntrinsic) MaxContentWidth(inlineRoot *LayoutNode, c IntrinsicContributor) (float32, error) {
+	var w float32
+	for _, child := range inlineRoot.Children {
+		switch child.Box {
+		case BoxText:
+			for _, word := range f.words[child.BoxID] {
+				w += word
+			}
+		case BoxInline:
+			start, end := c.InlineEdges(child)
+			cw, err := f.MaxContentWidth(child, c)
+			if err != nil {
+				return 0, err
+			}
+			w += start + cw + end
+		default:
+			cw, err := c.OuterMaxContentWidth(child)
+			if err != nil {
+				return 0, err
+			}
+			w += cw
+		}
+	}
+	return w, nil
+}
+
+func (f fakeInlineIntrinsic) MinContentWidth(inlineRoot *LayoutNode, c IntrinsicContributor) (float32, error) {
+	var w float32
+	for _, child := range inlineRoot.Children {
+		switch child.Box {
+		case BoxText:
+			for _, word := range f.words[child.BoxID] {
+				w = max(w, word)
+			}
+		case BoxInline:
+			cw, err := f.MinContentWidth(child, c)
+			if err != nil {
+				return 0, err
+			}
+			w = max(w, cw)
+		default:
+			cw, err := c.OuterMinContentWidth(child)
+			if err != nil {
+				return 0, err
+			}
+			w = max(w, cw)
+		}
+	}
+	return w, nil
+}
+
+func TestIntrinsicMeasurer_Recursive(t *testing.T) {
+	// root
+	//   a (block, padding 5): "30 50" <span margin 3>"20"</span> <inline-block margin 2>"40 10"</inline-block>
+	//   b (block, width 100px, margin-left 10)
+	text1 := &LayoutNode{BoxID: 4, Box: BoxText}
+	text2 := &LayoutNode{BoxID: 6, Box: BoxText}
+	span := &LayoutNode{BoxID: 5, Box: BoxInline, Children: []*LayoutNode{text2}}
+	text3 := &LayoutNode{BoxID: 9, Box: BoxText}
+	ib := &LayoutNode{BoxID: 7, Box: BoxInlineBlock, Children: []*LayoutNode{
+		{BoxID: 8, Box: BoxAnonymousInline, Children: []*LayoutNode{text3}},
+	}}
+	a := &LayoutNode{BoxID: 2, Box: BoxBlock, Children: []*LayoutNode{
+		{BoxID: 3, Box: BoxAnonymousInline, Children: []*LayoutNode{text1, span, ib}},
+	}}
+	b := &LayoutNode{BoxID: 10, Box: BoxBlock, Style: &ComputedStyle{Width: lenPx(100)}}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{a, b}}
+
+	used := UsedValuesTable{
+		a.BoxID:    {ContentWidth: 490, Padding: Edges{Left: 5, Right: 5}},
+		span.BoxID: {Margin: Edges{Left: 3, Right: 3}},
+		ib.BoxID:   {Margin: Edges{Left: 2, Right: 2}},
+		b.BoxID:    {ContentWidth: 100, Margin: Edges{Left: 10}},
+	}
+	inline := fakeInlineIntrinsic{words: map[BoxID][]float32{
+		text1.BoxID: {30, 50},
+		text2.BoxID: {20},
+		text3.BoxID: {40, 10},
+	}}
+	m := NewIntrinsicMeasurer(inline, used)
+
+	tests := []struct {
+		name     string
+		node     *LayoutNode
+		min, max float32
+	}{
+		{name: "inline_block", node: ib, min: 40, max: 50},
+		{name: "inline_content", node: a, min: 50, max: 30 + 50 + 26 + 54},
+		{name: "block_children", node: root, min: 110, max: 170},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minW, err := m.MinContentWidth(tt.node)
+			if err != nil {
+				t.Fatalf("MinContentWidth error: %v", err)
+			}
+			maxW, err := m.MaxContentWidth(tt.node)
+			if err != nil {
+				t.Fatalf("MaxContentWidth error: %v", err)
+			}
+			if minW != tt.min || maxW != tt.max {
+				t.Fatalf("expected min/max %v/%v, got %v/%v", tt.min, tt.max, minW, maxW)
+			}
+		})
+	}
+}
+
+func TestIntrinsicMeasurer_Replaced(t *testing.T) {
+	img := &LayoutNode{BoxID: 2, Box: BoxReplaced}
+	used := UsedValuesTable{img.BoxID: {Limits: &SizeLimits{MaxWidth: 120, MaxHeight: 1000}}}
+	m := NewIntrinsicMeasurer(fakeInlineIntrinsic{}, used)
+	w, err := m.MinContentWidth(img)
+	if err != nil {
+		t.Fatalf("MinContentWidth error: %v", err)
+	}
+	if w != 120 {
+		t.Fatalf("expected default object width clamped to 120, got %v", w)
+	}
+}
+
+func TestFlowLayout_DefaultIntrinsicMeasurer(t *testing.T) {
+	text := &LayoutNode{BoxID: 5, Box: BoxText}
+	ib := &LayoutNode{BoxID: 3, Box: BoxInlineBlock, Children: []*LayoutNode{
+		{BoxID: 4, Box: BoxAnonymousInline, Children: []*LayoutNode{text}},
+	}}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{
+		{BoxID: 2, Box: BoxAnonymousInline, Children: []*LayoutNode{ib}},
+	}}
+	used := UsedValuesTable{root.BoxID: {ContentWidth: 40}, ib.BoxID: {AutoWidth: true}}
+	inline := fakeInlineIntrinsic{words: map[BoxID][]float32{text.BoxID: {20, 30}}}
+
+	res, err := FlowLayout(root, used, inline, nil, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	if w := res.Geometry[ib.BoxID].Frame.W; w != 40 {
+		t.Fatalf("expected shrink-to-fit width 40, got %v", w)
+	}
+}
+
+func TestFlowLayout_IntrinsicWidthKeywords(t *testing.T) {
+	tests := []struct {
+		name  string
+		width Length
+		want  float32
+	}{
+		{name: "min_content", width: Length{Kind: LenMinContent}, want: 50},
+		{name: "max_content", width: Length{Kind: LenMaxContent}, want: 120},
+		{name: "fit_content", width: Length{Kind: LenFitContent}, want: 120},
+		{name: "fit_content_length", width: Length{Kind: LenFitContent, Value: 70}, want: 70},
+		{name: "fit_content_length_below_min", width: Length{Kind: LenFitContent, Value: 10}, want: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// root > box (width keyword) > inner (auto) > "40 30 50"
+			text := &LayoutNode{BoxID: 5, Box: BoxText}
+			inner := &LayoutNode{BoxID: 3, Box: BoxBlock, Children: []*LayoutNode{
+				{BoxID: 4, Box: BoxAnonymousInline, Children: []*LayoutNode{text}},
+			}}
+			box := &LayoutNode{BoxID: 2, Box: BoxBlock, Children: []*LayoutNode{inner},
+				Style: &ComputedStyle{Width: tt.width, Height: lenAuto()}}
+			root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{box}}
+
+			used, err := ResolveUsedValues(root, ResolveContext{ContainingBlock: Rect{W: 200}})
+			if err != nil {
+				t.Fatalf("ResolveUsedValues error: %v", err)
+			}
+			if used[box.BoxID].IntrinsicWidth == nil {
+				t.Fatalf("expected intrinsic width to be recorded")
+			}
+			inline := fakeInlineIntrinsic{words: map[BoxID][]float32{text.BoxID: {40, 30, 50}}}
+			res, err := FlowLayout(root, used, inline, nil, LayoutContext{}, LayoutOptions{})
+			if err != nil {
+				t.Fatalf("FlowLayout error: %v", err)
+			}
+			if w := res.Geometry[box.BoxID].Frame.W; w != tt.want {
+				t.Fatalf("expected width %v, got %v", tt.want, w)
+			}
+			if w := res.Geometry[inner.BoxID].Frame.W; w != tt.want {
+				t.Fatalf("expected auto-width child to fill %v, got %v", tt.want, w)
+			}
+		})
+	}
+}
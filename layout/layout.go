@@ -5,9 +5,9 @@ func BuildLayoutTree(renderRoot *RenderNode, opts BuildOptions) (*LayoutNode, er
 	if renderRoot == nil {
 		return nil, nil
 	}
-	gen := newBoxIDGen()
-	rootID := gen.newRoot(renderRoot.ID)
-	return buildBlockContainer(gen, renderRoot, BoxBlock, rootID)
+	b := newBuilder(opts)
+	rootID := b.newRoot(renderRoot.ID)
+	return buildBlockContainer(b, renderRoot, BoxBlock, rootID)
 }
 
 // E -> used values: resolve margins/padding/borders/widths into a table keyed by BoxID.
@@ -25,27 +25,37 @@ func FlowLayout(
 	root *LayoutNode,
 	used UsedValuesTable,
 	inline InlineLayouter, // black box, produces line boxes for BoxAnonymousInline
-	intrinsic IntrinsicMeasurer, // provides initial max-content approximations
+	intrinsic IntrinsicMeasurer, // min-/max-content widths; if nil, derived from inline if it is an InlineIntrinsic
 	ctx LayoutContext,
 	opts LayoutOptions,
 ) (*LayoutResult, error) {
 	if root == nil {
 		return &LayoutResult{
-			Root:     nil,
-			Geometry: make(LayoutGeometryTable),
-			Lines:    make(LinesByBlock),
+			Root:      nil,
+			Geometry:  make(LayoutGeometryTable),
+			Lines:     make(LinesByBlock),
+			Fragments: make(InlineFragmentsByBox),
 		}, nil
 	}
+	if intrinsic == nil {
+		if ii, ok := inline.(InlineIntrinsic); ok {
+			intrinsic = NewIntrinsicMeasurer(ii, used)
+		}
+	}
 	geom := make(LayoutGeometryTable)
 	lines := make(LinesByBlock)
-	err := layoutBlockContainer(root, used, geom, lines, inline, intrinsic)
+	inlines := newInlineState(root)
+	err := layoutBlockContainer(root, used, geom, lines, inlines, inline, intrinsic)
 	if err != nil {
 		return nil, err
 	}
+	frame := geom[root.BoxID].Frame
+	physicalizeGeometry(root, used[root.BoxID].WritingMode, frame.W, frame.H, used, geom)
 	return &LayoutResult{
-		Root:     root,
-		Geometry: geom,
-		Lines:    lines,
+		Root:      root,
+		Geometry:  geom,
+		Lines:     lines,
+		Fragments: inlines.frags,
 	}, nil
 }
 
@@ -65,6 +75,9 @@ type LayoutResult struct {
 	Root     *LayoutNode
 	Geometry LayoutGeometryTable
 	Lines    LinesByBlock // line boxes produced for each block container
+
+	// Fragments holds the per-line fragments of inline boxes (BoxInline).
+	Fragments InlineFragmentsByBox
 }
 
 func layoutBlockContainer(
@@ -72,13 +85,37 @@ func layoutBlockContainer(
 	used UsedValuesTable,
 	geom LayoutGeometryTable,
 	lines LinesByBlock,
+	inlines *inlineState,
 	inline InlineLayouter,
 	intrinsic IntrinsicMeasurer,
 ) error {
 	if node == nil {
 		return nil
 	}
+	width := used[node.BoxID].ContentWidth
+	if used[node.BoxID].IntrinsicWidth != nil {
+		var err error
+		if width, err = resolveIntrinsicWidth(node, width, used, intrinsic); err != nil {
+			return err
+		}
+	}
+	return layoutBlockContainerWidth(node, width, used, geom, lines, inlines, inline, intrinsic)
+}
+
+// layoutBlockContainerWidth lays out a block container with a given used
+// content width, e.g. the shrink-to-fit width of an inline-block.
+func layoutBlockContainerWidth(
+	node *LayoutNode,
+	contentWidth float32,
+	used UsedValuesTable,
+	geom LayoutGeometryTable,
+	lines LinesByBlock,
+	inlines *inlineState,
+	inline InlineLayouter,
+	intrinsic IntrinsicMeasurer,
+) error {
 	u := used[node.BoxID]
+	u.ContentWidth = contentWidth
 	content := Rect{
 		X: u.Border.Left + u.Padding.Left,
 		Y: u.Border.Top + u.Padding.Top,
@@ -90,39 +127,97 @@ func layoutBlockContainer(
 		W: u.ContentWidth + u.Padding.Left + u.Padding.Right + u.Border.Left + u.Border.Right,
 	}
 
+	var baseline float32
+	var hasBaseline bool
 	if isInlineOnlyBlockContainer(node) {
 		if inline == nil {
 			return errNotImplemented
 		}
 		inlineRoot := node.Children[0]
+		ictx := inlines.context(node, used, content.W)
 		lineBoxes, err := inline.LayoutInline(
 			inlineRoot,
 			content.W,
-			atomicSizer{inline: inline, intrinsic: intrinsic, used: used, geom: geom, lines: lines},
+			atomicSizer{inline: inline, intrinsic: intrinsic, used: used, geom: geom, lines: lines, inlines: inlines, mode: u.WritingMode},
+			ictx,
 		)
 		if err != nil {
 			return err
 		}
+		lineBoxes = clampLines(node.Style, lineBoxes)
 		if shouldStoreLines(node) {
 			lines[node.BoxID] = lineBoxes
 		}
+		inlines.collect(ictx, lineBoxes)
 		content.H = lineExtent(lineBoxes)
+		if n := len(lineBoxes); n > 0 {
+			last := lineBoxes[n-1]
+			baseline, hasBaseline = content.Y+last.Frame.Y+last.Baseline, true
+		}
 	} else {
-		h, err := layoutBlockChildrenVertical(node, node.Children, content, used, geom, lines, inline, intrinsic)
+		h, err := layoutBlockChildrenVertical(node, node.Children, content, used, geom, lines, inlines, inline, intrinsic)
 		if err != nil {
 			return err
 		}
 		content.H = h
+		baseline, hasBaseline = lastChildBaseline(node.Children, geom)
 	}
 
+	content.H = usedContentHeight(node, u, content.W, content.H)
 	frame.H = content.H + u.Padding.Top + u.Padding.Bottom + u.Border.Top + u.Border.Bottom
 
-	geom[node.BoxID] = LayoutGeometry{Frame: frame, Content: content}
+	geom[node.BoxID] = LayoutGeometry{Frame: frame, Content: content, Baseline: baseline, HasBaseline: hasBaseline}
 	node.Frame = frame
 	node.Content = content
 	return nil
 }
 
+// lastChildBaseline finds the baseline of the last in-flow line box among
+// block-level children, relative to the parent's border box.
+func lastChildBaseline(children []*LayoutNode, geom LayoutGeometryTable) (float32, bool) {
+	for i := len(children) - 1; i >= 0; i-- {
+		child := children[i]
+		if child == nil || child.Box == BoxReplaced {
+			continue
+		}
+		if g := geom[child.BoxID]; g.HasBaseline {
+			return g.Frame.Y + g.Baseline, true
+		}
+	}
+	return 0, false
+}
+
+// usedContentHeight determines the used content height of a block container
+// from its used values, given the width and the height of its laid-out contents.
+func usedContentHeight(node *LayoutNode, u UsedValues, width, contentHeight float32) float32 {
+	switch {
+	case u.HasHeight:
+		return u.Limits.clampHeight(u.ContentHeight)
+	case u.AspectRatio > 0:
+		h := u.Limits.clampHeight(width / u.AspectRatio)
+		if contentHeight > h && hasAutoMinHeight(node, u) {
+			// The automatic minimum size of a box with a preferred aspect ratio
+			// is its content size: overflowing content makes the box grow.
+			h = u.Limits.clampHeight(contentHeight)
+		}
+		return h
+	default:
+		return u.Limits.clampHeight(contentHeight)
+	}
+}
+
+// hasAutoMinHeight tells whether node's logical min-height is auto and its overflow is
+// visible, i.e. whether its automatic minimum height applies.
+func hasAutoMinHeight(node *LayoutNode, u UsedValues) bool {
+	if node.Style == nil {
+		return true
+	}
+	if node.Style.Overflow != OverflowVisible {
+		return false
+	}
+	return node.Style.MinMax == nil || usedStyle(node, u).MinMax.MinHeight.Kind == LenAuto
+}
+
 func layoutBlockChildrenVertical(
 	parent *LayoutNode,
 	children []*LayoutNode,
@@ -130,29 +225,50 @@ func layoutBlockChildrenVertical(
 	used UsedValuesTable,
 	geom LayoutGeometryTable,
 	lines LinesByBlock,
+	inlines *inlineState,
 	inline InlineLayouter,
 	intrinsic IntrinsicMeasurer,
 ) (contentHeight float32, err error) {
 	var y float32
+	mode := used[parent.BoxID].WritingMode
 	for _, child := range children {
 		if child == nil {
 			continue
 		}
 		cu := used[child.BoxID]
-		y += cu.Margin.Top
-		err := layoutBlockContainer(child, used, geom, lines, inline, intrinsic)
+		margin := marginsIn(cu, mode)
+		y += margin.Top
+		var err error
+		if child.Box == BoxReplaced {
+			// In an orthogonal flow, this is only an approximation of the
+			// available inline size.
+			avail := content.W - (margin.Left + margin.Right)
+			err = layoutReplaced(child, avail, used, geom, intrinsic)
+		} else {
+			err = layoutBlockContainer(child, used, geom, lines, inlines, inline, intrinsic)
+		}
 		if err != nil {
 			return 0, err
 		}
-		childGeom := geom[child.BoxID]
-		childGeom.Frame.X = content.X + cu.Margin.Left
+		childGeom := convertGeometry(geom[child.BoxID], cu.WritingMode, mode)
+		var auto marginAutoFlags
+		if cu.WritingMode == mode {
+			auto = cu.marginAuto
+		}
+		rtl := parent.Style != nil && parent.Style.Direction == DirectionRTL
+		left, _ := horizontalMargins(margin, auto, content.W, childGeom.Frame.W, rtl)
+		childGeom.Frame.X = content.X + left
 		childGeom.Frame.Y = content.Y + y
 		childGeom.Content.X += childGeom.Frame.X
 		childGeom.Content.Y += childGeom.Frame.Y
+		if child.Box == BoxReplaced {
+			childGeom.Object.X += childGeom.Frame.X
+			childGeom.Object.Y += childGeom.Frame.Y
+		}
 		geom[child.BoxID] = childGeom
 		child.Frame = childGeom.Frame
 		child.Content = childGeom.Content
-		y += childGeom.Frame.H + cu.Margin.Bottom
+		y += childGeom.Frame.H + margin.Bottom
 	}
 	return y, nil
 }
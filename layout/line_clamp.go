@@ -0,0 +1,16 @@
+package layout
+
+// Line clamping (CSS Overflow 4 §5). A block container with line-clamp keeps
+// at most that many line boxes of its inline content; the following lines are
+// hidden and take no space. Only the block container's own lines count, not
+// those of nested block containers. The ellipsis of the last line is inserted
+// after line breaking, see glyphing.TruncateLines.
+
+// clampLines returns the line boxes of a block container which remain
+// visible.
+func clampLines(block *ComputedStyle, lines []LineBox) []LineBox {
+	if block == nil || block.LineClamp <= 0 || len(lines) <= block.LineClamp {
+		return lines
+	}
+	return lines[:block.LineClamp]
+}
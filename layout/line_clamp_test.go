@@ -0,0 +1,35 @@
+package layout
+
+import "testing"
+
+func TestFlowLayout_LineClamp(t *testing.T) {
+	inlineRoot := &LayoutNode{BoxID: 2, Box: BoxAnonymousInline}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Style: &ComputedStyle{LineClamp: 2}, Children: []*LayoutNode{inlineRoot}}
+	used := UsedValuesTable{
+		root.BoxID:       {ContentWidth: 100},
+		inlineRoot.BoxID: {},
+	}
+	lines := []LineBox{
+		{Frame: Rect{Y: 0, H: 10}, Baseline: 8},
+		{Frame: Rect{Y: 10, H: 12}, Baseline: 9},
+		{Frame: Rect{Y: 22, H: 10}, Baseline: 8},
+	}
+	res, err := ComputeLayoutWithConstraints(
+		root,
+		used,
+		fakeInlineLayouter{lines: lines},
+		fakeIntrinsic{},
+		LayoutContext{ContainingBlock: Rect{W: 100}},
+		LayoutOptions{},
+	)
+	if err != nil {
+		t.Fatalf("ComputeLayoutWithConstraints error: %v", err)
+	}
+	g := res.Geometry[root.BoxID]
+	if g.Content.H != 22 || g.Baseline != 19 {
+		t.Fatalf("expected content height 22 and baseline 19, got %v and %v", g.Content.H, g.Baseline)
+	}
+	if got := len(res.Lines[root.BoxID]); got != 2 {
+		t.Fatalf("stored lines = %d, want 2", got)
+	}
+}
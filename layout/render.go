@@ -8,6 +8,7 @@ type RenderNode struct {
 	ID            NodeID
 	HTML          *html.Node
 	Styles        map[string]string
+	PseudoStyles  map[PseudoElement]map[string]string // computed styles of pseudo-elements
 	ChildrenNodes []*RenderNode
 }
 
@@ -31,3 +32,12 @@ func (r *RenderNode) ComputedStyle(prop string) string {
 	}
 	return r.Styles[prop]
 }
+
+// PseudoStyle returns a computed style property of one of r's pseudo-elements,
+// e.g. "content" or "display" of ::before.
+func (r *RenderNode) PseudoStyle(pseudo PseudoElement, prop string) string {
+	if r == nil || r.PseudoStyles == nil {
+		return ""
+	}
+	return r.PseudoStyles[pseudo][prop]
+}
@@ -0,0 +1,264 @@
+package layout
+
+import "golang.org/x/net/html"
+
+// Replaced elements are atomic boxes whose content lies outside the scope of
+// CSS formatting. Their used size follows CSS 2.1 §10.3.2 / §10.6.2 (with the
+// §10.4 constraint table for min/max sizes), based on natural dimensions
+// reported by a ReplacedMeasurer. The replaced content itself is placed inside
+// the content box according to object-fit and object-position.
+
+// Default object size for replaced content without natural dimensions.
+const (
+	defaultReplacedWidth  = 300
+	defaultReplacedHeight = 150
+)
+
+func isReplacedElement(n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	switch n.Data {
+	case "img", "video", "canvas", "iframe", "embed", "object", "svg":
+		return true
+	default:
+		return false
+	}
+}
+
+// Builds the flow item for a replaced element. Its children (fallback content)
+// are not rendered.
+func buildReplaced(b *builder, r *RenderNode, display string, parentBoxID BoxID) []FlowItem {
+	if display == "contents" { // behaves as display:none for replaced elements
+		return nil
+	}
+	b.counters.enter(r.ComputedStyle)
+	b.counters.leave()
+	node := &LayoutNode{
+		BoxID:  b.newChild(parentBoxID),
+		NodeID: r.ID,
+		Box:    BoxReplaced,
+		FC:     FCNone,
+	}
+	if display == "block" {
+		return []FlowItem{BlockItem(node)}
+	}
+	return []FlowItem{InlineItem(node)}
+}
+
+func naturalSize(intrinsic IntrinsicMeasurer, node *LayoutNode) (NaturalSize, error) {
+	rm, ok := intrinsic.(ReplacedMeasurer)
+	if !ok {
+		return NaturalSize{}, nil
+	}
+	natural, err := rm.NaturalSize(node)
+	if err != nil {
+		return NaturalSize{}, err
+	}
+	if natural.Ratio == 0 && natural.HasWidth && natural.HasHeight && natural.Height > 0 {
+		natural.Ratio = natural.Width / natural.Height
+	}
+	return natural, nil
+}
+
+// Lays out a replaced box with available width avail (for its margin-less
+// border box) and stores its geometry relative to its own border box origin.
+func layoutReplaced(
+	node *LayoutNode,
+	avail float32,
+	used UsedValuesTable,
+	geom LayoutGeometryTable,
+	intrinsic IntrinsicMeasurer,
+) error {
+	u := used[node.BoxID]
+	style := usedStyle(node, u)
+	natural, err := naturalSize(intrinsic, node)
+	if err != nil {
+		return err
+	}
+	natural = logicalNatural(natural, u.WritingMode)
+	hEdges := u.Padding.Left + u.Padding.Right + u.Border.Left + u.Border.Right
+	vEdges := u.Padding.Top + u.Padding.Bottom + u.Border.Top + u.Border.Bottom
+	sizing := replacedSizing(style, natural)
+	w, h := replacedContentSize(u, style.Width.Kind != LenAuto && !style.Width.isIntrinsic(), sizing, avail-hEdges)
+
+	content := Rect{X: u.Border.Left + u.Padding.Left, Y: u.Border.Top + u.Padding.Top, W: w, H: h}
+	frame := Rect{W: w + hEdges, H: h + vEdges}
+	object := objectRect(style, content, natural)
+
+	geom[node.BoxID] = LayoutGeometry{Frame: frame, Content: content, Object: object}
+	node.Frame = frame
+	node.Content = content
+	return nil
+}
+
+// replacedSizing returns the natural dimensions used for sizing: a preferred
+// aspect ratio overrides the natural one, unless it is "auto <ratio>" and the
+// content has a natural ratio.
+func replacedSizing(style ComputedStyle, natural NaturalSize) NaturalSize {
+	if r := style.AspectRatio; r.Ratio > 0 && (!r.Auto || natural.Ratio == 0) {
+		natural.Ratio = r.Ratio
+	}
+	return natural
+}
+
+// replacedContentSize computes the used content size of a replaced box.
+// hasWidth tells whether the computed width is not auto. A specified
+// dimension is clamped before the other one is derived from it (CSS 2.1 §10.4).
+func replacedContentSize(u UsedValues, hasWidth bool, natural NaturalSize, avail float32) (w, h float32) {
+	ratio := natural.Ratio
+	switch {
+	case hasWidth && u.HasHeight:
+		w, h = u.ContentWidth, u.ContentHeight
+	case hasWidth:
+		w = u.Limits.clampWidth(u.ContentWidth)
+		h = heightFromWidth(w, natural)
+	case u.HasHeight:
+		h = u.Limits.clampHeight(u.ContentHeight)
+		switch {
+		case ratio > 0:
+			w = h * ratio
+		case natural.HasWidth:
+			w = natural.Width
+		default:
+			w = defaultReplacedWidth
+		}
+	default:
+		switch {
+		case natural.HasWidth:
+			w = natural.Width
+		case natural.HasHeight && ratio > 0:
+			w = natural.Height * ratio
+		case ratio > 0 && avail > 0:
+			w = avail
+		default:
+			w = defaultReplacedWidth
+		}
+		h = heightFromWidth(w, natural)
+		if ratio > 0 {
+			return constrainWithRatio(w, h, u.Limits)
+		}
+	}
+	return u.Limits.clampWidth(w), u.Limits.clampHeight(h)
+}
+
+func heightFromWidth(w float32, natural NaturalSize) float32 {
+	switch {
+	case natural.Ratio > 0:
+		return w / natural.Ratio
+	case natural.HasHeight:
+		return natural.Height
+	default:
+		return defaultReplacedHeight
+	}
+}
+
+// constrainWithRatio applies min/max constraints to an auto-sized replaced box
+// with an aspect ratio, following the table in CSS 2.1 §10.4.
+func constrainWithRatio(w, h float32, l *SizeLimits) (float32, float32) {
+	if l == nil || w <= 0 || h <= 0 {
+		return l.clampWidth(w), l.clampHeight(h)
+	}
+	minW, minH := l.MinWidth, l.MinHeight
+	maxW, maxH := max(l.MaxWidth, minW), max(l.MaxHeight, minH)
+	switch {
+	case w > maxW && h > maxH:
+		if maxW/w <= maxH/h {
+			return maxW, max(minH, maxW*h/w)
+		}
+		return max(minW, maxH*w/h), maxH
+	case w < minW && h < minH:
+		if minW/w <= minH/h {
+			return min(maxW, minH*w/h), minH
+		}
+		return minW, min(maxH, minW*h/w)
+	case w < minW && h > maxH:
+		return minW, maxH
+	case w > maxW && h < minH:
+		return maxW, minH
+	case w > maxW:
+		return maxW, max(maxW*h/w, minH)
+	case w < minW:
+		return minW, min(minW*h/w, maxH)
+	case h > maxH:
+		return max(maxH*w/h, minW), maxH
+	case h < minH:
+		return min(minH*w/h, maxW), minH
+	}
+	return w, h
+}
+
+// objectRect places the replaced content inside the content box according to
+// object-fit and object-position. The result uses the same coordinate space
+// as content.
+func objectRect(style ComputedStyle, content Rect, natural NaturalSize) Rect {
+	ow, oh := content.W, content.H
+	ratio := natural.Ratio
+	switch style.ObjectFit {
+	case ObjectFitContain, ObjectFitCover:
+		if ratio > 0 {
+			ow, oh = fitToBox(content.W, content.H, ratio, style.ObjectFit == ObjectFitCover)
+		}
+	case ObjectFitNone, ObjectFitScaleDown:
+		ow, oh = naturalObjectSize(content, natural)
+		if style.ObjectFit == ObjectFitScaleDown && ratio > 0 {
+			if cw, ch := fitToBox(content.W, content.H, ratio, false); cw < ow {
+				ow, oh = cw, ch
+			}
+		}
+	}
+	return Rect{
+		X: content.X + positionOffset(style.ObjectPosition.X, content.W-ow, style.FontSizePx),
+		Y: content.Y + positionOffset(style.ObjectPosition.Y, content.H-oh, style.FontSizePx),
+		W: ow,
+		H: oh,
+	}
+}
+
+// fitToBox scales an object with the given ratio to be contained in (or to
+// cover) a w×h box.
+func fitToBox(w, h, ratio float32, cover bool) (float32, float32) {
+	if h <= 0 {
+		return w, w / ratio
+	}
+	wider := ratio > w/h
+	if wider != cover {
+		return w, w / ratio
+	}
+	return h * ratio, h
+}
+
+// naturalObjectSize is the object size used by object-fit:none, completing
+// missing natural dimensions from the ratio or the content box.
+func naturalObjectSize(content Rect, natural NaturalSize) (float32, float32) {
+	ratio := natural.Ratio
+	switch {
+	case natural.HasWidth && natural.HasHeight:
+		return natural.Width, natural.Height
+	case natural.HasWidth && ratio > 0:
+		return natural.Width, natural.Width / ratio
+	case natural.HasHeight && ratio > 0:
+		return natural.Height * ratio, natural.Height
+	case ratio > 0:
+		return fitToBox(content.W, content.H, ratio, false)
+	case natural.HasWidth:
+		return natural.Width, content.H
+	case natural.HasHeight:
+		return content.W, natural.Height
+	default:
+		return content.W, content.H
+	}
+}
+
+func positionOffset(l Length, free, fontSize float32) float32 {
+	switch l.Kind {
+	case LenPercent:
+		return free * l.Value
+	case LenEm:
+		return fontSize * l.Value
+	case LenAuto:
+		return free / 2
+	default:
+		return l.Value
+	}
+}
@@ -0,0 +1,169 @@
+package layout
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+type fakeReplacedIntrinsic struct {
+	fakeIntrinsic
+	natural NaturalSize
+}
+
+func (f fakeReplacedIntrinsic) NaturalSize(node *LayoutNode) (NaturalSize, error) {
+	return f.natural, nil
+}
+
+func newRenderImage(id NodeID, display string) *RenderNode {
+	return &RenderNode{
+		ID:     id,
+		HTML:   &html.Node{Type: html.ElementNode, Data: "img"},
+		Styles: map[string]string{"display": display},
+	}
+}
+
+func TestBuildInlineFlow_ReplacedElements(t *testing.T) {
+	gen, parentBoxID := newBoxGenWithRoot(1)
+
+	flow, err := buildInlineFlow(gen, newRenderImage(2, "inline"), parentBoxID)
+	if err != nil {
+		t.Fatalf("buildInlineFlow returned error: %v", err)
+	}
+	if len(flow) != 1 || flow[0].Kind != FlowInline || flow[0].Node.Box != BoxReplaced {
+		t.Fatalf("expected inline replaced box")
+	}
+
+	flow, err = buildInlineFlow(gen, newRenderImage(3, "block"), parentBoxID)
+	if err != nil {
+		t.Fatalf("buildInlineFlow returned error: %v", err)
+	}
+	if len(flow) != 1 || flow[0].Kind != FlowBlock || flow[0].Node.Box != BoxReplaced {
+		t.Fatalf("expected block-level replaced box")
+	}
+}
+
+func TestReplacedContentSize(t *testing.T) {
+	inf := float32(math.Inf(1))
+	image := NaturalSize{Width: 200, Height: 100, HasWidth: true, HasHeight: true, Ratio: 2}
+	tests := []struct {
+		name     string
+		used     UsedValues
+		hasWidth bool
+		natural  NaturalSize
+		w, h     float32
+	}{
+		{name: "natural", natural: image, w: 200, h: 100},
+		{name: "width_only", used: UsedValues{ContentWidth: 50}, hasWidth: true, natural: image, w: 50, h: 25},
+		{name: "height_only", used: UsedValues{ContentHeight: 60, HasHeight: true}, natural: image, w: 120, h: 60},
+		{name: "both", used: UsedValues{ContentWidth: 10, ContentHeight: 60, HasHeight: true}, hasWidth: true, natural: image, w: 10, h: 60},
+		{name: "no_natural_size", w: 300, h: 150},
+		{name: "ratio_only_fills", natural: NaturalSize{Ratio: 4}, w: 400, h: 100},
+		{
+			name:    "max_width_keeps_ratio",
+			used:    UsedValues{Limits: &SizeLimits{MaxWidth: 100, MaxHeight: inf}},
+			natural: image,
+			w:       100, h: 50,
+		},
+		{
+			name:     "clamped_width_keeps_ratio",
+			used:     UsedValues{ContentWidth: 400, Limits: &SizeLimits{MaxWidth: 200, MaxHeight: inf}},
+			hasWidth: true,
+			natural:  image,
+			w:        200, h: 100,
+		},
+		{
+			name:    "clamped_height_keeps_ratio",
+			used:    UsedValues{ContentHeight: 40, HasHeight: true, Limits: &SizeLimits{MinHeight: 80, MaxWidth: inf, MaxHeight: inf}},
+			natural: image,
+			w:       160, h: 80,
+		},
+		{
+			name:    "min_height_keeps_ratio",
+			used:    UsedValues{Limits: &SizeLimits{MinHeight: 200, MaxWidth: inf, MaxHeight: inf}},
+			natural: image,
+			w:       400, h: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := replacedContentSize(tt.used, tt.hasWidth, tt.natural, 400)
+			if w != tt.w || h != tt.h {
+				t.Fatalf("size = %vx%v, want %vx%v", w, h, tt.w, tt.h)
+			}
+		})
+	}
+}
+
+func TestObjectRect(t *testing.T) {
+	content := Rect{X: 5, Y: 5, W: 100, H: 100}
+	natural := NaturalSize{Width: 200, Height: 100, HasWidth: true, HasHeight: true, Ratio: 2}
+	center := Position{X: lenPct(0.5), Y: lenPct(0.5)}
+	tests := []struct {
+		fit  ObjectFit
+		pos  Position
+		want Rect
+	}{
+		{fit: ObjectFitFill, pos: center, want: Rect{X: 5, Y: 5, W: 100, H: 100}},
+		{fit: ObjectFitContain, pos: center, want: Rect{X: 5, Y: 30, W: 100, H: 50}},
+		{fit: ObjectFitCover, pos: center, want: Rect{X: -45, Y: 5, W: 200, H: 100}},
+		{fit: ObjectFitNone, pos: Position{}, want: Rect{X: 5, Y: 5, W: 200, H: 100}},
+		{fit: ObjectFitScaleDown, pos: Position{}, want: Rect{X: 5, Y: 5, W: 100, H: 50}},
+	}
+	for _, tt := range tests {
+		got := objectRect(ComputedStyle{ObjectFit: tt.fit, ObjectPosition: tt.pos}, content, natural)
+		if got != tt.want {
+			t.Fatalf("objectRect(fit=%d) = %+v, want %+v", tt.fit, got, tt.want)
+		}
+	}
+}
+
+func TestFlowLayout_BlockReplaced(t *testing.T) {
+	img := &LayoutNode{BoxID: 2, Box: BoxReplaced}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{img}}
+	used := UsedValuesTable{
+		root.BoxID: {ContentWidth: 300},
+		img.BoxID: {
+			Margin: Edges{Top: 10, Left: 20},
+			Border: Edges{Top: 1, Right: 1, Bottom: 1, Left: 1},
+		},
+	}
+	intrinsic := fakeReplacedIntrinsic{natural: NaturalSize{Width: 80, Height: 40, HasWidth: true, HasHeight: true}}
+
+	res, err := FlowLayout(root, used, fakeInlineLayouter{}, intrinsic, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	g := res.Geometry[img.BoxID]
+	if g.Frame != (Rect{X: 20, Y: 10, W: 82, H: 42}) {
+		t.Fatalf("replaced frame = %+v", g.Frame)
+	}
+	if g.Content != (Rect{X: 21, Y: 11, W: 80, H: 40}) || g.Object != g.Content {
+		t.Fatalf("replaced content = %+v, object = %+v", g.Content, g.Object)
+	}
+	if h := res.Geometry[root.BoxID].Content.H; h != 52 {
+		t.Fatalf("root content height = %v, want 52", h)
+	}
+}
+
+func TestAtomicSizer_InlineReplaced(t *testing.T) {
+	img := &LayoutNode{BoxID: 1, Box: BoxReplaced, Style: &ComputedStyle{Width: lenPx(50), Height: lenAuto()}}
+	used, err := ResolveUsedValues(img, ResolveContext{ContainingBlock: Rect{W: 300}})
+	if err != nil {
+		t.Fatalf("ResolveUsedValues error: %v", err)
+	}
+	a := atomicSizer{
+		intrinsic: fakeReplacedIntrinsic{natural: NaturalSize{Width: 200, Height: 100, HasWidth: true, HasHeight: true}},
+		used:      used,
+		geom:      make(LayoutGeometryTable),
+		lines:     make(LinesByBlock),
+	}
+	w, h, err := a.SizeInlineBlock(img, 300)
+	if err != nil {
+		t.Fatalf("SizeInlineBlock error: %v", err)
+	}
+	if w != 50 || h != 25 {
+		t.Fatalf("size = %vx%v, want 50x25", w, h)
+	}
+}
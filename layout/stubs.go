@@ -1,11 +1,19 @@
 package layout
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/npillmayer/css-box-layout/text"
+)
 
 type NodeID uint64
 type BoxID uint64
 
-type BuildOptions struct{}
+type BuildOptions struct {
+	// Text receives the text of generated content (::before/::after). It is
+	// required if the document has generated text.
+	Text text.TextStore
+}
 
 type LayoutOptions struct{}
 
@@ -0,0 +1,80 @@
+package layout
+
+// Text indentation (CSS Text 3 §8.1). FlowLayout resolves text-indent against
+// the used content width of a block container and hands it to the inline
+// layouter in InlineContext. Inline layouters break an indented line against
+// its reduced width only; glyphing.IndentLines moves the line afterwards.
+//
+// Anonymous block boxes have no style of their own and take text-indent from
+// the element that created them. Only the first formatted line of the element
+// is indented (CSS 2.1 §16.1), so the first line of an anonymous block counts
+// as first only if the block is the element's first child.
+
+// indentSource tells where the text-indent of a block container comes from.
+type indentSource struct {
+	style     *ComputedStyle // style holding text-indent; nil for the block's own
+	continued bool           // the block's first line is not its element's first formatted line
+}
+
+// anonymousIndents finds the text-indent source of the anonymous block boxes
+// under root.
+func anonymousIndents(root *LayoutNode) map[BoxID]indentSource {
+	indents := make(map[BoxID]indentSource)
+	var walk func(n *LayoutNode)
+	walk = func(n *LayoutNode) {
+		if n == nil {
+			return
+		}
+		for i, child := range n.Children {
+			if child != nil && child.Box == BoxAnonymousBlock && n.Style != nil {
+				indents[child.BoxID] = indentSource{style: n.Style, continued: i > 0}
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+	return indents
+}
+
+// usedTextIndent resolves the text-indent of a block container with the given
+// used content width.
+func usedTextIndent(block *ComputedStyle, contentWidth float32) float32 {
+	if block == nil {
+		return 0
+	}
+	switch l := block.TextIndent.Length; l.Kind {
+	case LenPx:
+		return l.Value
+	case LenPercent:
+		return contentWidth * l.Value
+	case LenEm:
+		return block.FontSizePx * l.Value
+	default:
+		return 0
+	}
+}
+
+// LineIndent returns the text-indent of a line box: first tells whether it is
+// the first line of the block container, afterForcedBreak whether the previous
+// line ended in a forced line break. Inline layouters break an indented line
+// with its available width reduced by the indent (a negative indent widens
+// it), but report the line box at the start of the line with the full width;
+// glyphing.IndentLines then moves and narrows it.
+func (c InlineContext) LineIndent(first, afterForcedBreak bool) float32 {
+	style := c.indent.style
+	if style == nil && c.Block != nil {
+		style = c.Block.Style
+	}
+	if c.TextIndent == 0 || style == nil {
+		return 0
+	}
+	ti := style.TextIndent
+	indented := first && !c.indent.continued || ti.EachLine && afterForcedBreak
+	if ti.Hanging {
+		indented = !indented
+	}
+	if indented {
+		return c.TextIndent
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package layout
+
+import "testing"
+
+func TestInlineContext_LineIndent(t *testing.T) {
+	tests := []struct {
+		name   string
+		indent TextIndent
+		want   [3]float32 // first line, line after a soft wrap, line after a forced break
+	}{
+		{name: "px", indent: TextIndent{Length: lenPx(20)}, want: [3]float32{20, 0, 0}},
+		{name: "percent", indent: TextIndent{Length: lenPct(0.1)}, want: [3]float32{30, 0, 0}},
+		{name: "each_line", indent: TextIndent{Length: lenPx(20), EachLine: true}, want: [3]float32{20, 0, 20}},
+		{name: "hanging", indent: TextIndent{Length: lenPx(20), Hanging: true}, want: [3]float32{0, 20, 20}},
+		{name: "hanging_each_line", indent: TextIndent{Length: lenPx(20), Hanging: true, EachLine: true}, want: [3]float32{0, 20, 0}},
+		{name: "negative", indent: TextIndent{Length: lenPx(-10)}, want: [3]float32{-10, 0, 0}},
+	}
+	for _, tt := range tests {
+		block := &LayoutNode{BoxID: 1, Box: BoxBlock, Style: &ComputedStyle{TextIndent: tt.indent}}
+		ctx := (*inlineState)(nil).context(block, nil, 300)
+		got := [3]float32{
+			ctx.LineIndent(true, false),
+			ctx.LineIndent(false, false),
+			ctx.LineIndent(false, true),
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected indents %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestInlineContext_LineIndentOfAnonymousBlocks(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		indent       TextIndent
+		want1, want2 float32 // first line of the first and of the second anonymous block
+	}{
+		{name: "first_child_only", indent: TextIndent{Length: lenPx(20)}, want1: 20},
+		{name: "hanging", indent: TextIndent{Length: lenPx(20), Hanging: true}, want2: 20},
+	} {
+		anon1 := &LayoutNode{BoxID: 2, Box: BoxAnonymousBlock}
+		anon2 := &LayoutNode{BoxID: 4, Box: BoxAnonymousBlock}
+		root := &LayoutNode{BoxID: 1, Box: BoxBlock, Style: &ComputedStyle{TextIndent: tt.indent, Direction: DirectionRTL},
+			Children: []*LayoutNode{anon1, {BoxID: 3, Box: BoxBlock}, anon2}}
+		s := newInlineState(root)
+		ctx := s.context(anon1, nil, 300)
+		if got := ctx.LineIndent(true, false); got != tt.want1 {
+			t.Errorf("%s: expected first anonymous block indent %v, got %v", tt.name, tt.want1, got)
+		}
+		if ctx.Direction != DirectionRTL {
+			t.Errorf("%s: expected the anonymous block to take the element's direction", tt.name)
+		}
+		if got := s.context(anon2, nil, 300).LineIndent(true, false); got != tt.want2 {
+			t.Errorf("%s: expected second anonymous block indent %v, got %v", tt.name, tt.want2, got)
+		}
+	}
+}
@@ -1,20 +1,64 @@
 package layout
 
+import "math"
+
 type UsedValuesTable map[BoxID]UsedValues
 
 type UsedValues struct {
-	Margin       Edges
-	Padding      Edges
-	Border       Edges
-	ContentWidth float32
+	Margin        Edges
+	Padding       Edges
+	Border        Edges
+	ContentWidth  float32
+	AutoWidth     bool        // width is auto and not transferred through an aspect ratio
+	ContentHeight float32     // valid if HasHeight
+	HasHeight     bool        // false: height is auto (content-based)
+	Limits        *SizeLimits // resolved min/max sizes; nil if unconstrained
+	AspectRatio   float32     // preferred width/height ratio of a non-replaced box; 0 if none
+
+	// WritingMode is the used writing mode; all other values are logical in
+	// it (see writing_mode.go). Boxes without a style inherit it.
+	WritingMode WritingMode
+
+	// marginAuto marks auto line-left and line-right margins. They count as 0
+	// in Margin and are resolved when a block-level box is placed.
+	marginAuto marginAutoFlags
+
+	// IntrinsicWidth is set for block containers with an intrinsic size keyword
+	// as width. FlowLayout measures the box and replaces ContentWidth (for
+	// block-level boxes resolved as the available width) before laying out
+	// its children.
+	IntrinsicWidth *IntrinsicWidth
+}
+
+// IntrinsicWidth records a width of min-content, max-content or fit-content.
+type IntrinsicWidth struct {
+	Kind  LengthKind // LenMinContent, LenMaxContent or LenFitContent
+	Limit float32    // fit-content(<length>): the resolved argument; 0 for the keyword
+
+	childCtx ResolveContext // context the children were resolved in
+}
+
+// SizeLimits are resolved min-/max-width and -height constraints of a box.
+// Max values are +Inf for "none".
+type SizeLimits struct {
+	MinWidth, MaxWidth   float32
+	MinHeight, MaxHeight float32
 }
 
 type ResolvePolicy struct{}
 
 type ResolveContext struct {
-	ContainingBlock Rect
+	ContainingBlock Rect // logical in WritingMode
 	FontSizePx      float32
 	Policy          ResolvePolicy
+
+	// WritingMode is the writing mode of the containing block.
+	WritingMode WritingMode
+	// Viewport is the physical size of the initial containing block. It is the
+	// available inline size of orthogonal flows in a containing block of
+	// indefinite block size; if zero, the containing block's inline size is
+	// used instead.
+	Viewport Rect
 }
 
 type EdgeLengths struct {
@@ -23,18 +67,255 @@ type EdgeLengths struct {
 
 type ComputedStyle struct {
 	Width      Length
+	Height     Length
+	MinMax     *MinMaxLengths // nil: initial values (no constraints)
 	Margin     EdgeLengths
 	Padding    EdgeLengths
 	Border     EdgeLengths
 	FontSizePx float32
+	LineHeight LineHeight
+
+	// Font selection (CSS Fonts 4 §2):
+	FontFamily  []string // family names and generic families, in priority order
+	FontWeight  uint16   // 1..1000; 0 means normal (400)
+	FontStyle   FontStyle
+	FontStretch float32 // percentage of the normal width; 0 means normal (100)
+
+	Direction       Direction
+	UnicodeBidi     UnicodeBidi
+	WritingMode     WritingMode
+	TextOrientation TextOrientation
+
+	AspectRatio   AspectRatio
+	Overflow      Overflow
+	VerticalAlign VerticalAlign
+
+	// Inline boxes:
+	BoxDecorationBreak BoxDecorationBreak
+
+	// Block containers (inline content):
+	TextAlign     TextAlign
+	TextAlignLast TextAlign // TextAlignAuto: derived from TextAlign
+	TextJustify   TextJustify
+	TextIndent    TextIndent
+	TextOverflow  TextOverflow
+	LineClamp     int            // line-clamp or -webkit-line-clamp; 0 means none
+	FirstLine     *ComputedStyle // ::first-line style, nil if none; see FirstLineStyle
+
+	// ::first-letter boxes:
+	InitialLetter InitialLetter
+
+	// Inline content (LenPx or LenEm; zero for "normal"):
+	LetterSpacing Length
+	WordSpacing   Length
+	WhiteSpace    WhiteSpace
+	TabSize       TabSize
+
+	// Replaced elements:
+	ObjectFit      ObjectFit
+	ObjectPosition Position
+
+	// Inheritance, to inherit again from ::first-line (see FirstLineStyle):
+	Parent        *ComputedStyle // style inherited from; nil if unknown
+	Specified     InheritedProps // properties set on the element, not inherited from Parent
+	FontSizeScale float32        // font-size as a multiple of Parent's (em, %); 0 if absolute
+}
+
+// MinMaxLengths holds min-width, max-width, min-height and max-height.
+// LenAuto means "auto" for the min values and "none" for the max values.
+type MinMaxLengths struct {
+	MinWidth, MaxWidth   Length
+	MinHeight, MaxHeight Length
+}
+
+// AspectRatio is the computed value of aspect-ratio. A zero Ratio means "auto".
+type AspectRatio struct {
+	Ratio float32 // width / height
+	Auto  bool    // "auto && <ratio>": a natural ratio of replaced content takes precedence
+}
+
+type Overflow uint8
+
+const (
+	OverflowVisible Overflow = iota
+	OverflowHidden
+	OverflowClip
+	OverflowScroll
+	OverflowAuto
+)
+
+// FontStyle is the computed value of font-style. Oblique angles are not
+// distinguished.
+type FontStyle uint8
+
+const (
+	FontStyleNormal FontStyle = iota
+	FontStyleItalic
+	FontStyleOblique
+)
+
+// Direction is the computed value of direction. In right-to-left block
+// containers, block-level children are placed from the right and text-align
+// start/end are mirrored.
+type Direction uint8
+
+const (
+	DirectionLTR Direction = iota
+	DirectionRTL
+)
+
+// UnicodeBidi is the computed value of unicode-bidi.
+type UnicodeBidi uint8
+
+const (
+	UnicodeBidiNormal UnicodeBidi = iota
+	UnicodeBidiEmbed
+	UnicodeBidiIsolate
+	UnicodeBidiOverride // bidi-override
+	UnicodeBidiIsolateOverride
+	UnicodeBidiPlaintext
+)
+
+// BoxDecorationBreak is the computed value of box-decoration-break.
+type BoxDecorationBreak uint8
+
+const (
+	BoxDecorationSlice BoxDecorationBreak = iota // edges only at the start of the first and the end of the last fragment
+	BoxDecorationClone                           // every fragment has its own padding, border and margins
+)
+
+// TextAlign is the computed value of text-align or text-align-last. start and
+// end refer to the direction of the block container.
+type TextAlign uint8
+
+const (
+	TextAlignAuto TextAlign = iota // text-align-last only; same as start for text-align
+	TextAlignStart
+	TextAlignEnd
+	TextAlignLeft
+	TextAlignRight
+	TextAlignCenter
+	TextAlignJustify
+)
+
+// TextIndent is the computed value of text-indent. Percentages refer to the
+// used content width of the block container.
+type TextIndent struct {
+	Length   Length
+	Hanging  bool // indent all lines except the first (and those after forced breaks with EachLine)
+	EachLine bool // also indent lines after forced line breaks
+}
+
+// TextJustify is the computed value of text-justify.
+type TextJustify uint8
+
+const (
+	TextJustifyAuto TextJustify = iota // inter-word
+	TextJustifyNone
+	TextJustifyInterWord
+	TextJustifyInterCharacter
+)
+
+// WhiteSpace is the computed value of white-space.
+type WhiteSpace uint8
+
+const (
+	WhiteSpaceNormal WhiteSpace = iota
+	WhiteSpaceNowrap
+	WhiteSpacePre
+	WhiteSpacePreWrap
+	WhiteSpacePreLine
+	WhiteSpaceBreakSpaces
+)
+
+// Wraps tells whether lines may break at soft wrap opportunities.
+func (ws WhiteSpace) Wraps() bool {
+	return ws != WhiteSpaceNowrap && ws != WhiteSpacePre
+}
+
+// PreservesNewlines tells whether segment breaks are kept as forced line
+// breaks, rather than being collapsed like spaces.
+func (ws WhiteSpace) PreservesNewlines() bool {
+	return ws != WhiteSpaceNormal && ws != WhiteSpaceNowrap
+}
+
+// PreservesTabs tells whether tabs are kept and advance to tab stops, rather
+// than being collapsed like spaces.
+func (ws WhiteSpace) PreservesTabs() bool {
+	return ws == WhiteSpacePre || ws == WhiteSpacePreWrap || ws == WhiteSpaceBreakSpaces
+}
+
+// TabSize is the computed value of tab-size.
+type TabSize struct {
+	Kind   TabSizeKind
+	Spaces float32 // TabSizeSpaces: multiple of the advance of a space
+	Length Length  // TabSizeLength: LenPx or LenEm
+}
+
+type TabSizeKind uint8
+
+const (
+	TabSizeInitial TabSizeKind = iota // 8 spaces
+	TabSizeSpaces
+	TabSizeLength
+)
+
+// TextOverflow is the computed value of text-overflow for the end of lines.
+type TextOverflow struct {
+	Kind   TextOverflowKind
+	String string // TextOverflowString only
 }
 
+type TextOverflowKind uint8
+
+const (
+	TextOverflowClip TextOverflowKind = iota
+	TextOverflowEllipsis
+	TextOverflowString
+)
+
+// VerticalAlign is the computed value of vertical-align.
+type VerticalAlign struct {
+	Kind  VerticalAlignKind
+	Shift Length // VAlignLength only; percentages refer to the box's line-height
+}
+
+type VerticalAlignKind uint8
+
+const (
+	VAlignBaseline VerticalAlignKind = iota
+	VAlignSub
+	VAlignSuper
+	VAlignTextTop
+	VAlignTextBottom
+	VAlignMiddle
+	VAlignTop    // aligned to the top of the line box
+	VAlignBottom // aligned to the bottom of the line box
+	VAlignLength
+)
+
+type ObjectFit uint8
+
+const (
+	ObjectFitFill ObjectFit = iota
+	ObjectFitContain
+	ObjectFitCover
+	ObjectFitNone
+	ObjectFitScaleDown
+)
+
+// Position is a two-dimensional position such as object-position. Percentages
+// refer to the free space between the box and the positioned object.
+type Position struct{ X, Y Length }
+
 func defaultComputedStyle() ComputedStyle {
 	return ComputedStyle{
-		Width:   Length{Kind: LenAuto},
-		Margin:  EdgeLengths{},
-		Padding: EdgeLengths{},
-		Border:  EdgeLengths{},
+		Width:          Length{Kind: LenAuto},
+		Height:         Length{Kind: LenAuto},
+		Margin:         EdgeLengths{},
+		Padding:        EdgeLengths{},
+		Border:         EdgeLengths{},
+		ObjectPosition: Position{X: Length{Kind: LenPercent, Value: 0.5}, Y: Length{Kind: LenPercent, Value: 0.5}},
 	}
 }
 
@@ -103,7 +384,85 @@ func resolveEdges(style ComputedStyle, ctx ResolveContext) (margin, padding, bor
 	return margin, padding, border, marginAuto
 }
 
+// horizontalMargins resolves the line-left and line-right margins of a
+// block-level box of width frameW in a containing block of width avail (CSS 2.1
+// §10.3.3). Auto margins take up the remaining space, sharing it if both are
+// auto. If the box is over-constrained, the end margin is ignored: the right
+// one if rtl is false, else the left one.
+func horizontalMargins(margin Edges, auto marginAutoFlags, avail, frameW float32, rtl bool) (left, right float32) {
+	left, right = margin.Left, margin.Right
+	remaining := avail - frameW - left - right
+	switch {
+	case remaining >= 0 && auto.Left && auto.Right:
+		return left + remaining/2, right + remaining/2
+	case remaining >= 0 && auto.Left:
+		return left + remaining, right
+	case remaining >= 0 && auto.Right:
+		return left, right + remaining
+	case rtl:
+		return left + remaining, right
+	default:
+		return left, right + remaining
+	}
+}
+
+// Resolves a height; percentages refer to the containing block's height and
+// compute to auto if that is not known.
+func resolveHeight(l Length, ctx ResolveContext) (px float32, isAuto bool) {
+	if l.Kind == LenPercent {
+		if ctx.ContainingBlock.H <= 0 {
+			return 0, true
+		}
+		return ctx.ContainingBlock.H * l.Value, false
+	}
+	return resolveLength(l, ctx)
+}
+
+func resolveSizeLimits(style ComputedStyle, ctx ResolveContext) *SizeLimits {
+	if style.MinMax == nil {
+		return nil
+	}
+	inf := float32(math.Inf(1))
+	limits := &SizeLimits{MaxWidth: inf, MaxHeight: inf}
+	if v, auto := resolveLength(style.MinMax.MinWidth, ctx); !auto {
+		limits.MinWidth = max(v, 0)
+	}
+	if v, auto := resolveLength(style.MinMax.MaxWidth, ctx); !auto {
+		limits.MaxWidth = max(v, 0)
+	}
+	if v, auto := resolveHeight(style.MinMax.MinHeight, ctx); !auto {
+		limits.MinHeight = max(v, 0)
+	}
+	if v, auto := resolveHeight(style.MinMax.MaxHeight, ctx); !auto {
+		limits.MaxHeight = max(v, 0)
+	}
+	return limits
+}
+
+// clampWidth applies min-/max-width; min wins over max (CSS 2.1 §10.4).
+func (l *SizeLimits) clampWidth(w float32) float32 {
+	if l == nil {
+		return w
+	}
+	return max(min(w, l.MaxWidth), l.MinWidth)
+}
+
+// clampHeight applies min-/max-height; min wins over max (CSS 2.1 §10.7).
+func (l *SizeLimits) clampHeight(h float32) float32 {
+	if l == nil {
+		return h
+	}
+	return max(min(h, l.MaxHeight), l.MinHeight)
+}
+
 func resolveContentWidth(kind BoxKind, style ComputedStyle, ctx ResolveContext, margin, padding, border Edges) float32 {
+	if kind == BoxReplaced {
+		// auto widths depend on natural dimensions and are resolved during flow layout.
+		if width, isAuto := resolveLength(style.Width, ctx); !isAuto && width > 0 {
+			return width
+		}
+		return 0
+	}
 	if !IsBlockLevel(kind) {
 		return 0
 	}
@@ -135,9 +494,18 @@ func styleOrDefault(s *ComputedStyle) ComputedStyle {
 
 func childResolveContext(node *LayoutNode, parent ResolveContext, used UsedValues) ResolveContext {
 	ctx := parent
+	ctx.WritingMode = used.WritingMode
 	if node != nil && IsBlockLevel(node.Box) && node.Box != BoxInlineBlock {
 		ctx.ContainingBlock.W = used.ContentWidth
 	}
+	if node != nil && IsBlockLevel(node.Box) {
+		ctx.ContainingBlock.H = 0
+		if used.HasHeight {
+			ctx.ContainingBlock.H = used.ContentHeight
+		} else if used.AspectRatio > 0 && node.Box != BoxInlineBlock {
+			ctx.ContainingBlock.H = used.Limits.clampHeight(used.ContentWidth / used.AspectRatio)
+		}
+	}
 	if node != nil && node.Box == BoxInlineBlock {
 		ctx.ContainingBlock.W = parent.ContainingBlock.W
 	}
@@ -147,13 +515,43 @@ func childResolveContext(node *LayoutNode, parent ResolveContext, used UsedValue
 	return ctx
 }
 
+// orthogonalContext maps the containing block of ctx to writing mode wm. In
+// an orthogonal flow the available inline size is the containing block's block
+// size, or the viewport's if that is indefinite (CSS Writing Modes 3 §7.3).
+func orthogonalContext(ctx ResolveContext, wm WritingMode) ResolveContext {
+	if !orthogonal(ctx.WritingMode, wm) {
+		return ctx
+	}
+	cb := ctx.ContainingBlock
+	ctx.ContainingBlock.W, ctx.ContainingBlock.H = cb.H, cb.W
+	if cb.H <= 0 {
+		vw, vh := ctx.Viewport.W, ctx.Viewport.H
+		if wm.IsVertical() {
+			vw = vh
+		}
+		ctx.ContainingBlock.W = vw
+		if vw <= 0 {
+			ctx.ContainingBlock.W = cb.W
+		}
+	}
+	ctx.WritingMode = wm
+	return ctx
+}
+
 func resolveUsedValues(node *LayoutNode, ctx ResolveContext, table UsedValuesTable) {
 	if node == nil {
 		return
 	}
-	style := styleOrDefault(node.Style)
+	wm := ctx.WritingMode
+	if node.Style != nil {
+		wm = node.Style.WritingMode
+	}
+	style := logicalStyle(styleOrDefault(node.Style), wm)
 
-	margin, padding, border, _ := resolveEdges(style, ctx)
+	// Percentages of margins and padding refer to the inline size of the
+	// containing block in its own writing mode.
+	margin, padding, border, marginAuto := resolveEdges(style, ctx)
+	ctx = orthogonalContext(ctx, wm)
 	contentW := resolveContentWidth(node.Box, style, ctx, margin, padding, border)
 
 	used := UsedValues{
@@ -161,10 +559,33 @@ func resolveUsedValues(node *LayoutNode, ctx ResolveContext, table UsedValuesTab
 		Padding:      padding,
 		Border:       border,
 		ContentWidth: contentW,
+		AutoWidth:    style.Width.Kind == LenAuto,
+		Limits:       resolveSizeLimits(style, ctx),
+		WritingMode:  wm,
+		marginAuto:   marginAuto,
+	}
+	if IsBlockLevel(node.Box) || node.Box == BoxReplaced {
+		if h, isAuto := resolveHeight(style.Height, ctx); !isAuto {
+			used.ContentHeight, used.HasHeight = max(h, 0), true
+		}
+	}
+	if IsBlockLevel(node.Box) && style.AspectRatio.Ratio > 0 {
+		used.AspectRatio = style.AspectRatio.Ratio
+		if style.Width.Kind == LenAuto && used.HasHeight {
+			// Definite height, auto width: the ratio transfers the height.
+			used.ContentWidth = used.Limits.clampWidth(used.ContentHeight * used.AspectRatio)
+			used.AutoWidth = false
+		}
+	}
+	childCtx := childResolveContext(node, ctx, used)
+	if IsBlockLevel(node.Box) && style.Width.isIntrinsic() {
+		used.IntrinsicWidth = &IntrinsicWidth{Kind: style.Width.Kind, childCtx: childCtx}
+		if style.Width.Kind == LenFitContent {
+			used.IntrinsicWidth.Limit = max(style.Width.Value, 0)
+		}
 	}
 	table[node.BoxID] = used
 
-	childCtx := childResolveContext(node, ctx, used)
 	for _, child := range node.Children {
 		resolveUsedValues(child, childCtx, table)
 	}
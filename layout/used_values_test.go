@@ -281,3 +281,67 @@ func TestResolveUsedValues_InlineInheritsWidth(t *testing.T) {
 		t.Fatalf("inline padding-left = %v, want 30", used[inlineChild.BoxID].Padding.Left)
 	}
 }
+
+func TestResolveUsedValues_InlineBlockAutoWidth(t *testing.T) {
+	auto := &LayoutNode{BoxID: 2, Box: BoxInlineBlock, Style: &ComputedStyle{Width: lenAuto()}}
+	zero := &LayoutNode{BoxID: 3, Box: BoxInlineBlock, Style: &ComputedStyle{Width: lenPx(0)}}
+	parent := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{auto, zero}}
+
+	used, err := ResolveUsedValues(parent, ResolveContext{ContainingBlock: Rect{W: 300}, FontSizePx: 16})
+	if err != nil {
+		t.Fatalf("ResolveUsedValues error: %v", err)
+	}
+	if u := used[auto.BoxID]; !u.AutoWidth {
+		t.Fatalf("expected width auto to be marked, got %+v", u)
+	}
+	if u := used[zero.BoxID]; u.AutoWidth || u.ContentWidth != 0 {
+		t.Fatalf("expected an explicit width of 0, got %+v", u)
+	}
+}
+
+func TestResolveUsedValues_AspectRatio(t *testing.T) {
+	parent := &LayoutNode{
+		BoxID: 1,
+		Box:   BoxBlock,
+		Style: &ComputedStyle{
+			Width:       lenAuto(),
+			Height:      lenAuto(),
+			AspectRatio: AspectRatio{Ratio: 2},
+		},
+	}
+	child := &LayoutNode{
+		BoxID: 2,
+		Box:   BoxBlock,
+		Style: &ComputedStyle{
+			Width:       lenAuto(),
+			Height:      lenPct(0.5),
+			AspectRatio: AspectRatio{Ratio: 3},
+			Margin:      EdgeLengths{Left: lenAuto(), Right: lenAuto()},
+		},
+	}
+	parent.Children = []*LayoutNode{child}
+
+	used, err := ResolveUsedValues(parent, ResolveContext{ContainingBlock: Rect{W: 200}, FontSizePx: 16})
+	if err != nil {
+		t.Fatalf("ResolveUsedValues error: %v", err)
+	}
+	if u := used[parent.BoxID]; u.AspectRatio != 2 || u.HasHeight {
+		t.Fatalf("parent used values = %+v, want ratio 2 with auto height", u)
+	}
+	// The parent's ratio-derived height (100) is definite for percentages.
+	u := used[child.BoxID]
+	if !u.HasHeight || u.ContentHeight != 50 {
+		t.Fatalf("child height = %v (definite %v), want 50", u.ContentHeight, u.HasHeight)
+	}
+	if u.ContentWidth != 150 {
+		t.Fatalf("child width = %v, want 150 (transferred from height)", u.ContentWidth)
+	}
+	// Auto margins are resolved against the transferred width.
+	res, err := FlowLayout(parent, used, fakeInlineLayouter{}, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	if f := res.Geometry[child.BoxID].Frame; f.X != 25 || f.W != 150 {
+		t.Fatalf("child frame = %+v, want 150 wide centered at 25", f)
+	}
+}
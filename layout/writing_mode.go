@@ -0,0 +1,296 @@
+package layout
+
+// Writing modes (CSS Writing Modes 3).
+//
+// Flow layout works in logical coordinates: X runs along the inline axis from
+// the line-left edge, Y along the block axis from the block-start edge, W is
+// an inline size and H a block size. In horizontal-tb these are the physical
+// coordinates. Used values of a box are logical in its own writing mode
+// (ContentWidth is its inline size, Margin.Left its line-left margin,
+// Margin.Top its block-start margin, etc.).
+//
+// While laying out, the geometry of a box is kept in the writing mode of the
+// block container laying it out; a child with a different writing mode is laid
+// out in its own and converted. FlowLayout finally maps all geometry to
+// physical coordinates. Line boxes, inline fragments and baselines stay in the
+// logical coordinates of their block container.
+
+// WritingMode is the computed value of writing-mode.
+type WritingMode uint8
+
+const (
+	HorizontalTB WritingMode = iota
+	VerticalRL
+	VerticalLR
+	SidewaysRL
+	SidewaysLR
+)
+
+// IsVertical tells whether the inline axis of wm is vertical.
+func (wm WritingMode) IsVertical() bool {
+	return wm != HorizontalTB
+}
+
+// blockFlipped tells whether the block-start edge is the physical right edge.
+func (wm WritingMode) blockFlipped() bool {
+	return wm == VerticalRL || wm == SidewaysRL
+}
+
+// lineFlipped tells whether the line-left edge is the physical bottom edge.
+func (wm WritingMode) lineFlipped() bool {
+	return wm == SidewaysLR
+}
+
+// orthogonal tells whether the inline axes of two writing modes are
+// perpendicular.
+func orthogonal(a, b WritingMode) bool {
+	return a.IsVertical() != b.IsVertical()
+}
+
+// TextOrientation is the computed value of text-orientation. It only affects
+// glyph orientation in vertical writing modes, see UsedTextOrientation.
+type TextOrientation uint8
+
+const (
+	TextOrientationMixed TextOrientation = iota
+	TextOrientationUpright
+	TextOrientationSideways
+)
+
+// UsedTextOrientation returns the orientation shapers should use for the text
+// of a box: sideways-* writing modes always set glyphs sideways, horizontal
+// ones upright.
+func UsedTextOrientation(style *ComputedStyle) TextOrientation {
+	s := styleOrDefault(style)
+	switch s.WritingMode {
+	case HorizontalTB:
+		return TextOrientationUpright
+	case SidewaysRL, SidewaysLR:
+		return TextOrientationSideways
+	default:
+		return s.TextOrientation
+	}
+}
+
+// LogicalEdges maps physical edges to line-relative ones: Left is line-left,
+// Right line-right, Top block-start and Bottom block-end.
+func (wm WritingMode) LogicalEdges(e Edges) Edges {
+	e.Top, e.Right, e.Bottom, e.Left = logicalEdges(wm, e.Top, e.Right, e.Bottom, e.Left)
+	return e
+}
+
+// PhysicalEdges is the inverse of LogicalEdges.
+func (wm WritingMode) PhysicalEdges(e Edges) Edges {
+	e.Top, e.Right, e.Bottom, e.Left = physicalEdges(wm, e.Top, e.Right, e.Bottom, e.Left)
+	return e
+}
+
+func logicalEdges[T any](wm WritingMode, top, right, bottom, left T) (bStart, lRight, bEnd, lLeft T) {
+	if !wm.IsVertical() {
+		return top, right, bottom, left
+	}
+	lLeft, lRight = top, bottom
+	if wm.lineFlipped() {
+		lLeft, lRight = bottom, top
+	}
+	bStart, bEnd = left, right
+	if wm.blockFlipped() {
+		bStart, bEnd = right, left
+	}
+	return bStart, lRight, bEnd, lLeft
+}
+
+func physicalEdges[T any](wm WritingMode, bStart, lRight, bEnd, lLeft T) (top, right, bottom, left T) {
+	if !wm.IsVertical() {
+		return bStart, lRight, bEnd, lLeft
+	}
+	top, bottom = lLeft, lRight
+	if wm.lineFlipped() {
+		top, bottom = lRight, lLeft
+	}
+	left, right = bStart, bEnd
+	if wm.blockFlipped() {
+		left, right = bEnd, bStart
+	}
+	return top, right, bottom, left
+}
+
+// PhysicalRect maps a logical rect inside a container of the given logical
+// size (inline size w, block size h) to physical coordinates relative to the
+// container's physical origin.
+func (wm WritingMode) PhysicalRect(r Rect, w, h float32) Rect {
+	if !wm.IsVertical() {
+		return r
+	}
+	p := Rect{X: r.Y, Y: r.X, W: r.H, H: r.W}
+	if wm.blockFlipped() {
+		p.X = h - (r.Y + r.H)
+	}
+	if wm.lineFlipped() {
+		p.Y = w - (r.X + r.W)
+	}
+	return p
+}
+
+// LogicalRect is the inverse of PhysicalRect; w and h are the physical width
+// and height of the container.
+func (wm WritingMode) LogicalRect(r Rect, w, h float32) Rect {
+	if !wm.IsVertical() {
+		return r
+	}
+	l := Rect{X: r.Y, Y: r.X, W: r.H, H: r.W}
+	if wm.lineFlipped() {
+		l.X = h - (r.Y + r.H)
+	}
+	if wm.blockFlipped() {
+		l.Y = w - (r.X + r.W)
+	}
+	return l
+}
+
+// physicalSize returns the physical width and height of a box with logical
+// inline size w and block size h.
+func (wm WritingMode) physicalSize(w, h float32) (float32, float32) {
+	if wm.IsVertical() {
+		return h, w
+	}
+	return w, h
+}
+
+// logicalStyle maps the physical properties of a style to the logical ones of
+// writing mode wm, so used values can be resolved with horizontal rules.
+func logicalStyle(s ComputedStyle, wm WritingMode) ComputedStyle {
+	if !wm.IsVertical() {
+		return s
+	}
+	s.Width, s.Height = s.Height, s.Width
+	if s.MinMax != nil {
+		mm := *s.MinMax
+		mm.MinWidth, mm.MinHeight = mm.MinHeight, mm.MinWidth
+		mm.MaxWidth, mm.MaxHeight = mm.MaxHeight, mm.MaxWidth
+		s.MinMax = &mm
+	}
+	s.Margin = logicalEdgeLengths(wm, s.Margin)
+	s.Padding = logicalEdgeLengths(wm, s.Padding)
+	s.Border = logicalEdgeLengths(wm, s.Border)
+	if s.AspectRatio.Ratio > 0 {
+		s.AspectRatio.Ratio = 1 / s.AspectRatio.Ratio
+	}
+	// Offsets of object-position along a flipped axis are still measured from
+	// the logical start; only percentages and auto are exact there.
+	s.ObjectPosition.X, s.ObjectPosition.Y = s.ObjectPosition.Y, s.ObjectPosition.X
+	if wm.blockFlipped() && s.ObjectPosition.Y.Kind == LenPercent {
+		s.ObjectPosition.Y.Value = 1 - s.ObjectPosition.Y.Value
+	}
+	if wm.lineFlipped() && s.ObjectPosition.X.Kind == LenPercent {
+		s.ObjectPosition.X.Value = 1 - s.ObjectPosition.X.Value
+	}
+	return s
+}
+
+func logicalEdgeLengths(wm WritingMode, e EdgeLengths) EdgeLengths {
+	e.Top, e.Right, e.Bottom, e.Left = logicalEdges(wm, e.Top, e.Right, e.Bottom, e.Left)
+	return e
+}
+
+// usedStyle returns the style of node in the logical terms of its used
+// writing mode.
+func usedStyle(node *LayoutNode, u UsedValues) ComputedStyle {
+	return logicalStyle(styleOrDefault(node.Style), u.WritingMode)
+}
+
+// logicalNatural maps the natural dimensions of replaced content to writing
+// mode wm.
+func logicalNatural(n NaturalSize, wm WritingMode) NaturalSize {
+	if !wm.IsVertical() {
+		return n
+	}
+	n.Width, n.Height = n.Height, n.Width
+	n.HasWidth, n.HasHeight = n.HasHeight, n.HasWidth
+	if n.Ratio > 0 {
+		n.Ratio = 1 / n.Ratio
+	}
+	return n
+}
+
+// marginsIn returns the margins of a box with used values u in the logical
+// terms of writing mode wm.
+func marginsIn(u UsedValues, wm WritingMode) Edges {
+	if u.WritingMode == wm {
+		return u.Margin
+	}
+	return wm.LogicalEdges(u.WritingMode.PhysicalEdges(u.Margin))
+}
+
+// convertGeometry maps the geometry of a box, laid out at the origin in its
+// own writing mode from, to writing mode to. Baselines are dropped if the
+// inline axes are orthogonal.
+func convertGeometry(g LayoutGeometry, from, to WritingMode) LayoutGeometry {
+	if from == to {
+		return g
+	}
+	w, h := g.Frame.W, g.Frame.H
+	pw, ph := from.physicalSize(w, h)
+	conv := func(r Rect) Rect {
+		return to.LogicalRect(from.PhysicalRect(r, w, h), pw, ph)
+	}
+	g.Frame, g.Content, g.Object = conv(g.Frame), conv(g.Content), conv(g.Object)
+	switch {
+	case orthogonal(from, to):
+		g.Baseline, g.HasBaseline = 0, false
+	case from.blockFlipped() != to.blockFlipped():
+		g.Baseline = h - g.Baseline
+	}
+	return g
+}
+
+// physicalizeGeometry maps the geometry of node and its descendants from
+// logical to physical coordinates. Geometry of a box is logical in mode, the
+// writing mode of the box laying it out, whose border box has logical size
+// w×h. margins holds the margins of an atomic inline in mode: its geometry is
+// relative to its own margin box.
+func physicalizeGeometry(node *LayoutNode, mode WritingMode, w, h float32, used UsedValuesTable, geom LayoutGeometryTable) {
+	if node == nil {
+		return
+	}
+	g, ok := geom[node.BoxID]
+	if !ok {
+		for _, child := range node.Children {
+			physicalizeAtomic(child, mode, used, geom)
+		}
+		return
+	}
+	own := used[node.BoxID].WritingMode
+	frame := mode.PhysicalRect(g.Frame, w, h)
+	ow, oh := frame.W, frame.H
+	if own.IsVertical() {
+		ow, oh = oh, ow
+	}
+	g.Frame = frame
+	g.Content = mode.PhysicalRect(g.Content, w, h)
+	if node.Box == BoxReplaced {
+		g.Object = mode.PhysicalRect(g.Object, w, h)
+	}
+	geom[node.BoxID] = g
+	node.Frame, node.Content = g.Frame, g.Content
+	for _, child := range node.Children {
+		physicalizeGeometry(child, own, ow, oh, used, geom)
+	}
+}
+
+// physicalizeAtomic descends through inline content in writing mode mode and
+// maps atomic inlines relative to their margin boxes.
+func physicalizeAtomic(node *LayoutNode, mode WritingMode, used UsedValuesTable, geom LayoutGeometryTable) {
+	if node == nil {
+		return
+	}
+	g, ok := geom[node.BoxID]
+	if !ok {
+		for _, child := range node.Children {
+			physicalizeAtomic(child, mode, used, geom)
+		}
+		return
+	}
+	m := marginsIn(used[node.BoxID], mode)
+	physicalizeGeometry(node, mode, g.Frame.X+g.Frame.W+m.Right, g.Frame.Y+g.Frame.H+m.Bottom, used, geom)
+}
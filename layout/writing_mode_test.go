@@ -0,0 +1,117 @@
+package layout
+
+import "testing"
+
+func TestWritingMode_Mapping(t *testing.T) {
+	phys := Edges{Top: 1, Right: 2, Bottom: 3, Left: 4}
+	tests := []struct {
+		mode    WritingMode
+		logical Edges // Top: block-start, Right: line-right, Bottom: block-end, Left: line-left
+		rect    Rect  // physical rect of logical {X: 10, Y: 20, W: 30, H: 40} in a 100×200 (inline×block) container
+	}{
+		{mode: HorizontalTB, logical: phys, rect: Rect{X: 10, Y: 20, W: 30, H: 40}},
+		{mode: VerticalRL, logical: Edges{Top: 2, Right: 3, Bottom: 4, Left: 1}, rect: Rect{X: 140, Y: 10, W: 40, H: 30}},
+		{mode: VerticalLR, logical: Edges{Top: 4, Right: 3, Bottom: 2, Left: 1}, rect: Rect{X: 20, Y: 10, W: 40, H: 30}},
+		{mode: SidewaysRL, logical: Edges{Top: 2, Right: 3, Bottom: 4, Left: 1}, rect: Rect{X: 140, Y: 10, W: 40, H: 30}},
+		{mode: SidewaysLR, logical: Edges{Top: 4, Right: 1, Bottom: 2, Left: 3}, rect: Rect{X: 20, Y: 60, W: 40, H: 30}},
+	}
+	for _, tt := range tests {
+		logical := tt.mode.LogicalEdges(phys)
+		if logical != tt.logical {
+			t.Errorf("mode %d: expected logical edges %+v, got %+v", tt.mode, tt.logical, logical)
+		}
+		if back := tt.mode.PhysicalEdges(logical); back != phys {
+			t.Errorf("mode %d: edges do not round-trip: %+v", tt.mode, back)
+		}
+		r := Rect{X: 10, Y: 20, W: 30, H: 40}
+		p := tt.mode.PhysicalRect(r, 100, 200)
+		if p != tt.rect {
+			t.Errorf("mode %d: expected physical rect %+v, got %+v", tt.mode, tt.rect, p)
+		}
+		pw, ph := tt.mode.physicalSize(100, 200)
+		if back := tt.mode.LogicalRect(p, pw, ph); back != r {
+			t.Errorf("mode %d: rect does not round-trip: %+v", tt.mode, back)
+		}
+	}
+}
+
+func TestFlowLayout_VerticalRLBlockStacking(t *testing.T) {
+	// Blocks stack from right to left; widths are block sizes.
+	c1 := &LayoutNode{BoxID: 2, Box: BoxBlock, Style: &ComputedStyle{
+		WritingMode: VerticalRL, Width: lenPx(30), Height: lenAuto(), Margin: EdgeLengths{Right: lenPx(10)},
+	}}
+	c2 := &LayoutNode{BoxID: 3, Box: BoxBlock, Style: &ComputedStyle{WritingMode: VerticalRL, Width: lenPx(50), Height: lenAuto()}}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{c1, c2}, Style: &ComputedStyle{
+		WritingMode: VerticalRL, Width: lenAuto(), Height: lenPx(200),
+	}}
+	used, err := ResolveUsedValues(root, ResolveContext{ContainingBlock: Rect{W: 400, H: 300}})
+	if err != nil {
+		t.Fatalf("ResolveUsedValues error: %v", err)
+	}
+	if u := used[c1.BoxID]; u.ContentWidth != 200 || u.Margin.Top != 10 || u.WritingMode != VerticalRL {
+		t.Fatalf("expected logical used values (inline size 200, block-start margin 10), got %+v", u)
+	}
+	res, err := FlowLayout(root, used, fakeInlineLayouter{}, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	want := map[BoxID]Rect{
+		root.BoxID: {W: 90, H: 200},
+		c1.BoxID:   {X: 50, W: 30, H: 200},
+		c2.BoxID:   {X: 0, W: 50, H: 200},
+	}
+	for id, r := range want {
+		if got := res.Geometry[id].Frame; got != r {
+			t.Errorf("box %d: expected frame %+v, got %+v", id, r, got)
+		}
+	}
+}
+
+// maxWidthLayouter records the available inline size and returns one line
+// filling it.
+type maxWidthLayouter struct {
+	maxWidth *float32
+}
+
+func (f maxWidthLayouter) LayoutInline(inlineRoot *LayoutNode, maxWidth float32, atomic AtomicSizer, ctx InlineContext) ([]LineBox, error) {
+	*f.maxWidth = maxWidth
+	return []LineBox{{Frame: Rect{W: maxWidth, H: 20}}}, nil
+}
+
+func TestFlowLayout_OrthogonalFlow(t *testing.T) {
+	// A vertical-lr block in a horizontal block of auto height takes the
+	// viewport height as its available inline size; its top margin is a
+	// line-left margin.
+	text := &LayoutNode{BoxID: 4, Box: BoxText}
+	v := &LayoutNode{BoxID: 2, Box: BoxBlock, Style: &ComputedStyle{
+		WritingMode: VerticalLR, Width: lenAuto(), Height: lenAuto(), Margin: EdgeLengths{Top: lenPx(5)},
+	}, Children: []*LayoutNode{{BoxID: 3, Box: BoxAnonymousInline, Children: []*LayoutNode{text}}}}
+	after := &LayoutNode{BoxID: 5, Box: BoxBlock, Style: &ComputedStyle{Height: lenPx(10)}}
+	root := &LayoutNode{BoxID: 1, Box: BoxBlock, Children: []*LayoutNode{v, after},
+		Style: &ComputedStyle{Width: lenPx(300), Height: lenAuto()}}
+
+	used, err := ResolveUsedValues(root, ResolveContext{
+		ContainingBlock: Rect{W: 800},
+		Viewport:        Rect{W: 800, H: 600},
+	})
+	if err != nil {
+		t.Fatalf("ResolveUsedValues error: %v", err)
+	}
+	var maxWidth float32
+	res, err := FlowLayout(root, used, maxWidthLayouter{&maxWidth}, fakeIntrinsic{}, LayoutContext{}, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("FlowLayout error: %v", err)
+	}
+	if maxWidth != 595 {
+		t.Fatalf("expected available inline size 595, got %v", maxWidth)
+	}
+	if g := res.Geometry[v.BoxID]; g.Frame != (Rect{X: 0, Y: 5, W: 20, H: 595}) {
+		t.Fatalf("expected vertical block frame {0 5 20 595}, got %+v", g.Frame)
+	}
+	if g := res.Geometry[after.BoxID]; g.Frame.Y != 600 {
+		t.Fatalf("expected following block at y=600, got %v", g.Frame.Y)
+	}
+	if h := res.Geometry[root.BoxID].Frame.H; h != 610 {
+		t.Fatalf("expected root height 610, got %v", h)
+	}
+}
@@ -0,0 +1,109 @@
+package sfnt
+
+import "sort"
+
+// cmap maps characters to glyphs with a format 4 or format 12 subtable.
+type cmap struct {
+	format uint16
+	data   []byte // the subtable
+}
+
+// parseCmap selects the Unicode subtable with the widest coverage.
+func parseCmap(data []byte) (cmap, error) {
+	type candidate struct {
+		platform, encoding uint16
+		format             uint16
+		rank               int
+	}
+	// Preference order: full Unicode repertoire first, then the BMP.
+	ranks := map[candidate]int{
+		{platform: 3, encoding: 10, format: 12}: 1,
+		{platform: 0, encoding: 6, format: 12}:  2,
+		{platform: 0, encoding: 4, format: 12}:  3,
+		{platform: 3, encoding: 1, format: 4}:   4,
+		{platform: 0, encoding: 3, format: 4}:   5,
+	}
+	best, bestRank := cmap{}, len(ranks)+1
+	n := int(u16(data, 2))
+	for i := 0; i < n; i++ {
+		rec := 4 + 8*i
+		off := int(u32(data, rec+4))
+		if rec+8 > len(data) || off+4 > len(data) {
+			return cmap{}, errInvalid
+		}
+		c := candidate{platform: u16(data, rec), encoding: u16(data, rec+2), format: u16(data, off)}
+		rank, ok := ranks[c]
+		if !ok || rank >= bestRank {
+			continue
+		}
+		sub := data[off:]
+		var length int
+		if c.format == 12 {
+			length = int(u32(sub, 4))
+		} else {
+			length = int(u16(sub, 2))
+		}
+		if length > len(sub) {
+			// Format 4 lengths of large tables are sometimes truncated to 16 bits.
+			length = len(sub)
+		}
+		best, bestRank = cmap{format: c.format, data: sub[:length]}, rank
+	}
+	if best.data == nil {
+		return cmap{}, errUnsupported
+	}
+	return best, nil
+}
+
+func (c cmap) lookup(r rune) uint16 {
+	switch c.format {
+	case 4:
+		return c.lookup4(r)
+	case 12:
+		return c.lookup12(r)
+	}
+	return 0
+}
+
+func (c cmap) lookup4(r rune) uint16 {
+	if r < 0 || r > 0xFFFF {
+		return 0
+	}
+	d := c.data
+	segs := int(u16(d, 6)) / 2
+	endCodes, startCodes := 14, 16+2*segs
+	deltas, rangeOffsets := 16+4*segs, 16+6*segs
+	i := sort.Search(segs, func(i int) bool { return rune(u16(d, endCodes+2*i)) >= r })
+	if i == segs {
+		return 0
+	}
+	start := rune(u16(d, startCodes+2*i))
+	if r < start {
+		return 0
+	}
+	delta := u16(d, deltas+2*i)
+	ro := int(u16(d, rangeOffsets+2*i))
+	if ro == 0 {
+		return uint16(r) + delta
+	}
+	g := u16(d, rangeOffsets+2*i+ro+2*int(r-start))
+	if g == 0 {
+		return 0
+	}
+	return g + delta
+}
+
+func (c cmap) lookup12(r rune) uint16 {
+	d := c.data
+	n := int(u32(d, 12))
+	i := sort.Search(n, func(i int) bool { return rune(u32(d, 16+12*i+4)) >= r })
+	if i == n {
+		return 0
+	}
+	g := 16 + 12*i
+	start := rune(u32(d, g))
+	if r < start {
+		return 0
+	}
+	return uint16(u32(d, g+8) + uint32(r-start))
+}
@@ -0,0 +1,274 @@
+// Package sfnt reads the metrics of OpenType and TrueType fonts: vertical
+// font metrics, character-to-glyph mapping, horizontal advances and kerning
+// pairs of the legacy kern table. It does not read outlines, and GPOS
+// kerning and complex-script layout are out of scope.
+//
+// All values are in font units; scale them by size/UnitsPerEm.
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Font holds the metrics of a single font face.
+type Font struct {
+	UnitsPerEm uint16
+	Ascent     int16 // above the baseline, positive
+	Descent    int16 // below the baseline, positive
+	LineGap    int16
+	XHeight    int16 // 0 if the font does not record it
+	CapHeight  int16 // 0 if the font does not record it
+	NumGlyphs  uint16
+
+	cmap     cmap
+	advances []uint16 // per hmtx long metric; later glyphs repeat the last
+	kern     map[uint32]int16
+}
+
+var (
+	errInvalid     = errors.New("sfnt: invalid font data")
+	errUnsupported = errors.New("sfnt: unsupported font format")
+)
+
+// Open reads the font in the file at path. For font collections, the first
+// font is read.
+func Open(path string) (*Font, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(f, fi.Size())
+}
+
+// Parse reads a font from the size bytes of r. For font collections, the
+// first font is read.
+func Parse(r io.ReaderAt, size int64) (*Font, error) {
+	tables, err := readDirectory(r, size)
+	if err != nil {
+		return nil, err
+	}
+	f := &Font{}
+	head, err := tables.read(r, "head", 54)
+	if err != nil {
+		return nil, err
+	}
+	f.UnitsPerEm = u16(head, 18)
+	if f.UnitsPerEm == 0 {
+		return nil, errInvalid
+	}
+	hhea, err := tables.read(r, "hhea", 36)
+	if err != nil {
+		return nil, err
+	}
+	f.Ascent, f.Descent, f.LineGap = i16(hhea, 4), -i16(hhea, 6), i16(hhea, 8)
+	maxp, err := tables.read(r, "maxp", 6)
+	if err != nil {
+		return nil, err
+	}
+	f.NumGlyphs = u16(maxp, 4)
+	if err := f.readHmtx(r, tables, u16(hhea, 34)); err != nil {
+		return nil, err
+	}
+	if err := f.readOS2(r, tables); err != nil {
+		return nil, err
+	}
+	data, err := tables.read(r, "cmap", 4)
+	if err != nil {
+		return nil, err
+	}
+	if f.cmap, err = parseCmap(data); err != nil {
+		return nil, err
+	}
+	if err := f.readKern(r, tables); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// GlyphIndex returns the glyph of r, or false if the font has none.
+func (f *Font) GlyphIndex(r rune) (uint16, bool) {
+	g := f.cmap.lookup(r)
+	return g, g != 0
+}
+
+// Advance returns the horizontal advance of glyph g.
+func (f *Font) Advance(g uint16) uint16 {
+	if len(f.advances) == 0 {
+		return 0
+	}
+	if int(g) >= len(f.advances) {
+		return f.advances[len(f.advances)-1]
+	}
+	return f.advances[g]
+}
+
+// Kern returns the kerning adjustment between glyphs left and right.
+func (f *Font) Kern(left, right uint16) int16 {
+	return f.kern[uint32(left)<<16|uint32(right)]
+}
+
+type tableRecord struct{ offset, length uint32 }
+
+type directory map[string]tableRecord
+
+// readDirectory reads the table directory and checks that all tables lie
+// within the size bytes of r, before any of them is allocated.
+func readDirectory(r io.ReaderAt, size int64) (directory, error) {
+	var hdr [12]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return nil, fmt.Errorf("sfnt: reading header: %w", err)
+	}
+	var base int64
+	switch string(hdr[:4]) {
+	case "\x00\x01\x00\x00", "OTTO", "true":
+	case "ttcf":
+		if binary.BigEndian.Uint32(hdr[8:]) == 0 {
+			return nil, errInvalid
+		}
+		var off [4]byte
+		if _, err := r.ReadAt(off[:], 12); err != nil {
+			return nil, fmt.Errorf("sfnt: reading collection: %w", err)
+		}
+		base = int64(binary.BigEndian.Uint32(off[:]))
+		if _, err := r.ReadAt(hdr[:], base); err != nil {
+			return nil, fmt.Errorf("sfnt: reading header: %w", err)
+		}
+	default:
+		return nil, errUnsupported
+	}
+	n := int(u16(hdr[:], 4))
+	if base+12+16*int64(n) > size {
+		return nil, errInvalid
+	}
+	records := make([]byte, 16*n)
+	if _, err := r.ReadAt(records, base+12); err != nil {
+		return nil, fmt.Errorf("sfnt: reading table directory: %w", err)
+	}
+	dir := make(directory, n)
+	for i := 0; i < n; i++ {
+		rec := records[16*i:]
+		tag, t := string(rec[:4]), tableRecord{offset: u32(rec, 8), length: u32(rec, 12)}
+		if int64(t.offset)+int64(t.length) > size {
+			return nil, fmt.Errorf("sfnt: %q table past the end of the data", tag)
+		}
+		dir[tag] = t
+	}
+	return dir, nil
+}
+
+// read returns the contents of table tag, which must be at least minLen bytes
+// long.
+func (d directory) read(r io.ReaderAt, tag string, minLen int) ([]byte, error) {
+	rec, ok := d[tag]
+	if !ok {
+		return nil, fmt.Errorf("sfnt: missing %q table", tag)
+	}
+	if int(rec.length) < minLen {
+		return nil, fmt.Errorf("sfnt: %q table too short", tag)
+	}
+	data := make([]byte, rec.length)
+	if _, err := r.ReadAt(data, int64(rec.offset)); err != nil {
+		return nil, fmt.Errorf("sfnt: reading %q table: %w", tag, err)
+	}
+	return data, nil
+}
+
+func (f *Font) readHmtx(r io.ReaderAt, tables directory, n uint16) error {
+	if n == 0 {
+		return errInvalid
+	}
+	hmtx, err := tables.read(r, "hmtx", 4*int(n))
+	if err != nil {
+		return err
+	}
+	f.advances = make([]uint16, n)
+	for i := range f.advances {
+		f.advances[i] = u16(hmtx, 4*i)
+	}
+	return nil
+}
+
+// readOS2 reads x-height and cap-height and, if the font asks for it, uses the
+// typographic metrics for ascent, descent and line gap. The table is optional.
+func (f *Font) readOS2(r io.ReaderAt, tables directory) error {
+	if _, ok := tables["OS/2"]; !ok {
+		return nil
+	}
+	os2, err := tables.read(r, "OS/2", 78)
+	if err != nil {
+		return err
+	}
+	const useTypoMetrics = 1 << 7
+	if u16(os2, 62)&useTypoMetrics != 0 {
+		f.Ascent, f.Descent, f.LineGap = i16(os2, 68), -i16(os2, 70), i16(os2, 72)
+	}
+	if u16(os2, 0) >= 2 && len(os2) >= 90 {
+		f.XHeight, f.CapHeight = i16(os2, 86), i16(os2, 88)
+	}
+	return nil
+}
+
+// readKern reads the horizontal format 0 subtables of a version 0 kern table.
+// The table is optional.
+func (f *Font) readKern(r io.ReaderAt, tables directory) error {
+	if _, ok := tables["kern"]; !ok {
+		return nil
+	}
+	kern, err := tables.read(r, "kern", 4)
+	if err != nil {
+		return err
+	}
+	if u16(kern, 0) != 0 {
+		return nil // Apple's 32-bit header variant
+	}
+	f.kern = make(map[uint32]int16)
+	off := 4
+	for i := 0; i < int(u16(kern, 2)) && off+6 <= len(kern); i++ {
+		length, coverage := int(u16(kern, off+2)), u16(kern, off+4)
+		const horizontal, minimum, crossStream = 1, 2, 4
+		if coverage>>8 == 0 && off+14 <= len(kern) {
+			n := int(u16(kern, off+6))
+			if off+14+6*n > len(kern) {
+				return errInvalid
+			}
+			if coverage&(horizontal|minimum|crossStream) == horizontal {
+				for j := 0; j < n; j++ {
+					p := off + 14 + 6*j
+					f.kern[u32(kern, p)] += i16(kern, p+4)
+				}
+			}
+			// The 16-bit length overflows for large subtables.
+			length = max(length, 14+6*n)
+		}
+		if length < 6 {
+			return errInvalid
+		}
+		off += length
+	}
+	return nil
+}
+
+func u16(b []byte, off int) uint16 {
+	if off+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[off:])
+}
+
+func i16(b []byte, off int) int16 { return int16(u16(b, off)) }
+
+func u32(b []byte, off int) uint32 {
+	if off+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[off:])
+}
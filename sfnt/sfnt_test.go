@@ -0,0 +1,178 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fontBuilder assembles an sfnt file from raw tables.
+type fontBuilder map[string][]byte
+
+func (fb fontBuilder) bytes() []byte {
+	tags := make([]string, 0, len(fb))
+	for tag := range fb {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var out bytes.Buffer
+	w := func(v any) { binary.Write(&out, binary.BigEndian, v) }
+	w(uint32(0x00010000))
+	w(uint16(len(tags)))
+	w([3]uint16{})
+	offset := 12 + 16*len(tags)
+	for _, tag := range tags {
+		out.WriteString(tag)
+		w(uint32(0))
+		w(uint32(offset))
+		w(uint32(len(fb[tag])))
+		offset += len(fb[tag])
+	}
+	for _, tag := range tags {
+		out.Write(fb[tag])
+	}
+	return out.Bytes()
+}
+
+func table(values ...any) []byte {
+	var out bytes.Buffer
+	for _, v := range values {
+		binary.Write(&out, binary.BigEndian, v)
+	}
+	return out.Bytes()
+}
+
+func testFont(useTypo bool) fontBuilder {
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	hhea := make([]byte, 36)
+	copy(hhea[4:], table(int16(800), int16(-200), int16(50)))
+	binary.BigEndian.PutUint16(hhea[34:], 3)
+	os2 := make([]byte, 96)
+	binary.BigEndian.PutUint16(os2[0:], 4)
+	if useTypo {
+		binary.BigEndian.PutUint16(os2[62:], 1<<7)
+	}
+	copy(os2[68:], table(int16(750), int16(-250), int16(100)))
+	copy(os2[86:], table(int16(500), int16(700)))
+
+	format4, format12 := cmapFormat4(), cmapFormat12()
+	cmap := table(uint16(0), uint16(2),
+		uint16(3), uint16(1), uint32(20),
+		uint16(3), uint16(10), uint32(20+len(format4)))
+	cmap = append(append(cmap, format4...), format12...)
+
+	kern := table(uint16(0), uint16(1), // version, nTables
+		uint16(0), uint16(14+6), uint16(1), // subtable version, length, coverage
+		uint16(1), uint16(6), uint16(0), uint16(0), // nPairs, search params
+		uint16(1), uint16(2), int16(-80)) // glyphs of A and B
+
+	return fontBuilder{
+		"head": head,
+		"hhea": hhea,
+		"maxp": table(uint32(0x5000), uint16(4)),
+		"hmtx": table(uint16(500), int16(0), uint16(600), int16(0), uint16(700), int16(0), int16(0)),
+		"OS/2": os2,
+		"cmap": cmap,
+		"kern": kern,
+	}
+}
+
+// cmapFormat4 maps 'A'..'C' to 1..3 by delta and 'x' to 3 through the glyph
+// ID array.
+func cmapFormat4() []byte {
+	sub := table(
+		uint16(4), uint16(0), uint16(0), // format, length (patched), language
+		uint16(6), uint16(0), uint16(0), uint16(0), // segCountX2, search params
+		[]uint16{'C', 'x', 0xFFFF}, uint16(0), // endCode, reservedPad
+		[]uint16{'A', 'x', 0xFFFF}, // startCode
+		[]int16{1 - 'A', 0, 1},     // idDelta
+		[]uint16{0, 4, 0},          // idRangeOffset
+		uint16(3),                  // glyphIdArray
+	)
+	binary.BigEndian.PutUint16(sub[2:], uint16(len(sub)))
+	return sub
+}
+
+// cmapFormat12 maps the characters of cmapFormat4 as it does, and U+1F600
+// to 2.
+func cmapFormat12() []byte {
+	groups := [][3]uint32{{'A', 'C', 1}, {'x', 'x', 3}, {0x1F600, 0x1F600, 2}}
+	return table(uint16(12), uint16(0), uint32(16+12*len(groups)), uint32(0), uint32(len(groups)), groups)
+}
+
+func TestParseCmap_Format4(t *testing.T) {
+	sub := cmapFormat4()
+	c, err := parseCmap(append(table(uint16(0), uint16(1), uint16(3), uint16(1), uint32(12)), sub...))
+	if err != nil {
+		t.Fatalf("parseCmap error: %v", err)
+	}
+	for r, want := range map[rune]uint16{'A': 1, 'C': 3, 'x': 3, 'D': 0, 0x1F600: 0} {
+		if g := c.lookup(r); g != want {
+			t.Errorf("lookup(%U) = %d, want %d", r, g, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := testFont(false).bytes()
+	f, err := Parse(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if f.UnitsPerEm != 1000 || f.Ascent != 800 || f.Descent != 200 || f.LineGap != 50 ||
+		f.XHeight != 500 || f.CapHeight != 700 || f.NumGlyphs != 4 {
+		t.Fatalf("unexpected metrics: %+v", f)
+	}
+	glyphs := map[rune]uint16{'A': 1, 'B': 2, 'C': 3, 'x': 3, 0x1F600: 2, 'D': 0, 0x1F601: 0}
+	for r, want := range glyphs {
+		if g, ok := f.GlyphIndex(r); g != want || ok != (want != 0) {
+			t.Errorf("GlyphIndex(%U) = %d, %v; want %d", r, g, ok, want)
+		}
+	}
+	for g, want := range []uint16{500, 600, 700, 700} {
+		if adv := f.Advance(uint16(g)); adv != want {
+			t.Errorf("Advance(%d) = %d, want %d", g, adv, want)
+		}
+	}
+	if k := f.Kern(1, 2); k != -80 {
+		t.Errorf("Kern(1, 2) = %d, want -80", k)
+	}
+	if k := f.Kern(2, 1); k != 0 {
+		t.Errorf("Kern(2, 1) = %d, want 0", k)
+	}
+}
+
+func TestParse_TypoMetrics(t *testing.T) {
+	data := testFont(true).bytes()
+	f, err := Parse(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if f.Ascent != 750 || f.Descent != 250 || f.LineGap != 100 {
+		t.Fatalf("expected typographic metrics, got %d/%d/%d", f.Ascent, f.Descent, f.LineGap)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if _, err := Parse(bytes.NewReader([]byte("wOFF0000000000000000")), 20); err == nil {
+		t.Errorf("expected error for WOFF data")
+	}
+	fb := testFont(false)
+	delete(fb, "cmap")
+	data := fb.bytes()
+	if _, err := Parse(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Errorf("expected error for a missing cmap table")
+	}
+	// The length of the first table record claims 4 GB.
+	data = testFont(false).bytes()
+	binary.BigEndian.PutUint32(data[12+12:], 0xFFFFFFFF)
+	if _, err := Parse(bytes.NewReader(data), int64(len(data))); err == nil || !strings.Contains(err.Error(), "past the end") {
+		t.Errorf("expected error for a table past the end of the data, got %v", err)
+	}
+	if _, err := Parse(bytes.NewReader(data[:20]), 20); err == nil {
+		t.Errorf("expected error for a truncated table directory")
+	}
+}
@@ -0,0 +1,627 @@
+package text
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+//go:generate sh -c "python3 gen_bidi_tables.py $UCD > bidi_tables.go"
+
+// BidiClass is the Bidi_Class property of a character (UAX #9, Table 4).
+type BidiClass uint8
+
+const (
+	BidiL BidiClass = iota
+	BidiR
+	BidiAL
+	BidiEN
+	BidiES
+	BidiET
+	BidiAN
+	BidiCS
+	BidiNSM
+	BidiBN
+	BidiB
+	BidiS
+	BidiWS
+	BidiON
+	BidiLRE
+	BidiLRO
+	BidiRLE
+	BidiRLO
+	BidiPDF
+	BidiLRI
+	BidiRLI
+	BidiFSI
+	BidiPDI
+)
+
+type bidiRange struct {
+	Lo, Hi rune
+	Class  BidiClass
+}
+
+// LookupBidiClass returns the Bidi_Class of r.
+func LookupBidiClass(r rune) BidiClass {
+	i := sort.Search(len(bidiClasses), func(i int) bool { return bidiClasses[i].Hi >= r })
+	if i < len(bidiClasses) && bidiClasses[i].Lo <= r {
+		return bidiClasses[i].Class
+	}
+	return BidiL
+}
+
+// Direction is a base direction of a paragraph.
+type Direction uint8
+
+const (
+	DirectionLTR  Direction = iota
+	DirectionRTL            // right-to-left
+	DirectionAuto           // from the first strong character (rules P2, P3)
+)
+
+// Level is a bidi embedding level. Odd levels are right-to-left.
+type Level uint8
+
+func (l Level) IsRTL() bool { return l&1 == 1 }
+
+const maxBidiDepth = 125
+
+// BidiLevels resolves the embedding levels of the text s following the Unicode
+// Bidirectional Algorithm (UAX #9) up to and including rule L1 for the end of
+// each paragraph. Explicit embeddings and isolates are given by the
+// directional formatting characters in s. The result holds one level per byte
+// of s; characters removed by rule X9 get the level of the preceding
+// character. Paragraphs are separated by characters of class B.
+func BidiLevels(s string, dir Direction) []Level {
+	p := newBidiParagraphs(s)
+	levels := make([]Level, len(s))
+	for start := 0; start < len(p.runes); {
+		end := start
+		for end < len(p.runes) && p.orig[end] != BidiB {
+			end++
+		}
+		if end < len(p.runes) {
+			end++ // include the paragraph separator
+		}
+		p.resolveParagraph(start, end, dir)
+		start = end
+	}
+	for i, pos := range p.pos {
+		n := len(s) - pos
+		if i+1 < len(p.pos) {
+			n = p.pos[i+1] - pos
+		}
+		for j := 0; j < n; j++ {
+			levels[pos+j] = p.levels[i]
+		}
+	}
+	return levels
+}
+
+type bidiParagraphs struct {
+	runes   []rune
+	pos     []int       // byte offset of each rune
+	orig    []BidiClass // original classes
+	classes []BidiClass // classes being resolved
+	levels  []Level
+	removed []bool // removed by rule X9
+	match   []int  // index of the matching PDI of an isolate initiator, or of the matching initiator of a PDI; -1 if none
+}
+
+func newBidiParagraphs(s string) *bidiParagraphs {
+	p := &bidiParagraphs{}
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				r = 0xFFFD
+			}
+		}
+		p.runes = append(p.runes, r)
+		p.pos = append(p.pos, i)
+		p.orig = append(p.orig, LookupBidiClass(r))
+	}
+	n := len(p.runes)
+	p.classes = append([]BidiClass(nil), p.orig...)
+	p.levels = make([]Level, n)
+	p.removed = make([]bool, n)
+	p.match = make([]int, n)
+	return p
+}
+
+func isIsolateInitiator(c BidiClass) bool { return c == BidiLRI || c == BidiRLI || c == BidiFSI }
+
+func isRemovedByX9(c BidiClass) bool {
+	switch c {
+	case BidiLRE, BidiRLE, BidiLRO, BidiRLO, BidiPDF, BidiBN:
+		return true
+	}
+	return false
+}
+
+// matchIsolates pairs isolate initiators with PDIs in [start, end) (BD9).
+func (p *bidiParagraphs) matchIsolates(start, end int) {
+	var stack []int
+	for i := start; i < end; i++ {
+		p.match[i] = -1
+		switch {
+		case isIsolateInitiator(p.orig[i]):
+			stack = append(stack, i)
+		case p.orig[i] == BidiPDI && len(stack) > 0:
+			opener := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			p.match[opener], p.match[i] = i, opener
+		}
+	}
+}
+
+// firstStrong implements rules P2 and P3 on [start, end): the level of the
+// first strong character not inside an isolate, or def if there is none.
+func (p *bidiParagraphs) firstStrong(start, end int, def Level) Level {
+	for i := start; i < end; i++ {
+		switch c := p.orig[i]; {
+		case c == BidiL:
+			return 0
+		case c == BidiR || c == BidiAL:
+			return 1
+		case isIsolateInitiator(c):
+			if p.match[i] < 0 {
+				return def
+			}
+			i = p.match[i]
+		case c == BidiB:
+			return def
+		}
+	}
+	return def
+}
+
+func (p *bidiParagraphs) resolveParagraph(start, end int, dir Direction) {
+	p.matchIsolates(start, end)
+	var level Level
+	switch dir {
+	case DirectionRTL:
+		level = 1
+	case DirectionAuto:
+		level = p.firstStrong(start, end, 0)
+	}
+	p.resolveExplicit(start, end, level)
+	for _, seq := range p.isolatingRunSequences(start, end, level) {
+		p.resolveWeak(seq)
+		p.resolveBrackets(seq)
+		p.resolveNeutral(seq)
+		p.resolveImplicit(seq)
+	}
+	p.resetWhitespace(start, end, level)
+}
+
+// resolveExplicit implements rules X1–X9.
+func (p *bidiParagraphs) resolveExplicit(start, end int, paraLevel Level) {
+	type entry struct {
+		level    Level
+		override BidiClass // BidiON for neutral
+		isolate  bool
+	}
+	stack := []entry{{level: paraLevel, override: BidiON}}
+	var overflowIsolates, overflowEmbeddings, validIsolates int
+
+	nextLevel := func(rtl bool) Level {
+		l := stack[len(stack)-1].level
+		if rtl {
+			return (l + 1) | 1
+		}
+		return (l + 2) &^ 1
+	}
+	for i := start; i < end; i++ {
+		top := stack[len(stack)-1]
+		switch c := p.orig[i]; c {
+		case BidiRLE, BidiLRE, BidiRLO, BidiLRO:
+			p.levels[i] = top.level
+			l := nextLevel(c == BidiRLE || c == BidiRLO)
+			if l <= maxBidiDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				e := entry{level: l, override: BidiON}
+				if c == BidiRLO {
+					e.override = BidiR
+				} else if c == BidiLRO {
+					e.override = BidiL
+				}
+				stack = append(stack, e)
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+		case BidiRLI, BidiLRI, BidiFSI:
+			p.levels[i] = top.level
+			if top.override != BidiON {
+				p.classes[i] = top.override
+			}
+			rtl := c == BidiRLI
+			if c == BidiFSI {
+				stop := end
+				if p.match[i] >= 0 {
+					stop = p.match[i]
+				}
+				rtl = p.firstStrong(i+1, stop, 0) == 1
+			}
+			l := nextLevel(rtl)
+			if l <= maxBidiDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				validIsolates++
+				stack = append(stack, entry{level: l, override: BidiON, isolate: true})
+			} else {
+				overflowIsolates++
+			}
+		case BidiPDI:
+			if overflowIsolates > 0 {
+				overflowIsolates--
+			} else if validIsolates > 0 {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			p.levels[i] = top.level
+			if top.override != BidiON {
+				p.classes[i] = top.override
+			}
+		case BidiPDF:
+			p.levels[i] = top.level
+			switch {
+			case overflowIsolates > 0:
+			case overflowEmbeddings > 0:
+				overflowEmbeddings--
+			case !top.isolate && len(stack) >= 2:
+				stack = stack[:len(stack)-1]
+			}
+		case BidiB:
+			p.levels[i] = paraLevel
+		case BidiBN:
+			p.levels[i] = top.level
+		default:
+			p.levels[i] = top.level
+			if top.override != BidiON {
+				p.classes[i] = top.override
+			}
+		}
+		p.removed[i] = isRemovedByX9(p.orig[i])
+	}
+}
+
+// isolatingRunSequences implements rule X10 (BD13): it returns the isolating
+// run sequences of [start, end) as lists of indices of non-removed characters.
+func (p *bidiParagraphs) isolatingRunSequences(start, end int, paraLevel Level) []bidiSequence {
+	// level runs
+	var runs [][]int
+	var run []int
+	for i := start; i < end; i++ {
+		if p.removed[i] {
+			continue
+		}
+		if len(run) > 0 && p.levels[run[len(run)-1]] != p.levels[i] {
+			runs = append(runs, run)
+			run = nil
+		}
+		run = append(run, i)
+	}
+	if len(run) > 0 {
+		runs = append(runs, run)
+	}
+	runOf := make(map[int]int) // first index of a run -> run number
+	for n, r := range runs {
+		runOf[r[0]] = n
+	}
+
+	var seqs []bidiSequence
+	for _, r := range runs {
+		if first := r[0]; p.orig[first] == BidiPDI && p.match[first] >= 0 {
+			continue // continues the sequence of its isolate initiator
+		}
+		var indices []int
+		for {
+			indices = append(indices, r...)
+			last := r[len(r)-1]
+			if !isIsolateInitiator(p.orig[last]) || p.match[last] < 0 {
+				break
+			}
+			n, ok := runOf[p.match[last]]
+			if !ok {
+				break
+			}
+			r = runs[n]
+		}
+		seqs = append(seqs, p.newSequence(indices, start, end, paraLevel))
+	}
+	return seqs
+}
+
+type bidiSequence struct {
+	indices  []int
+	level    Level
+	sos, eos BidiClass
+}
+
+func (p *bidiParagraphs) newSequence(indices []int, start, end int, paraLevel Level) bidiSequence {
+	seq := bidiSequence{indices: indices, level: p.levels[indices[0]]}
+	before := paraLevel
+	for i := indices[0] - 1; i >= start; i-- {
+		if !p.removed[i] {
+			before = p.levels[i]
+			break
+		}
+	}
+	after := paraLevel
+	last := indices[len(indices)-1]
+	if !isIsolateInitiator(p.orig[last]) {
+		for i := last + 1; i < end; i++ {
+			if !p.removed[i] {
+				after = p.levels[i]
+				break
+			}
+		}
+	}
+	seq.sos = directionOf(max(before, seq.level))
+	seq.eos = directionOf(max(after, p.levels[last]))
+	return seq
+}
+
+func directionOf(l Level) BidiClass {
+	if l.IsRTL() {
+		return BidiR
+	}
+	return BidiL
+}
+
+// resolveWeak implements rules W1–W7.
+func (p *bidiParagraphs) resolveWeak(seq bidiSequence) {
+	idx := seq.indices
+	cls := p.classes
+	// W1
+	prev := seq.sos
+	for _, i := range idx {
+		if cls[i] == BidiNSM {
+			if isIsolateInitiator(prev) || prev == BidiPDI {
+				cls[i] = BidiON
+			} else {
+				cls[i] = prev
+			}
+		}
+		prev = cls[i]
+	}
+	// W2, W3
+	strong := seq.sos
+	for _, i := range idx {
+		switch cls[i] {
+		case BidiL, BidiR, BidiAL:
+			strong = cls[i]
+		case BidiEN:
+			if strong == BidiAL {
+				cls[i] = BidiAN
+			}
+		}
+	}
+	for _, i := range idx {
+		if cls[i] == BidiAL {
+			cls[i] = BidiR
+		}
+	}
+	// W4
+	for k := 1; k+1 < len(idx); k++ {
+		c, before, after := cls[idx[k]], cls[idx[k-1]], cls[idx[k+1]]
+		switch {
+		case c == BidiES && before == BidiEN && after == BidiEN:
+			cls[idx[k]] = BidiEN
+		case c == BidiCS && before == BidiEN && after == BidiEN:
+			cls[idx[k]] = BidiEN
+		case c == BidiCS && before == BidiAN && after == BidiAN:
+			cls[idx[k]] = BidiAN
+		}
+	}
+	// W5
+	for k := 0; k < len(idx); k++ {
+		if cls[idx[k]] != BidiET {
+			continue
+		}
+		j := k
+		for j < len(idx) && cls[idx[j]] == BidiET {
+			j++
+		}
+		if (k > 0 && cls[idx[k-1]] == BidiEN) || (j < len(idx) && cls[idx[j]] == BidiEN) {
+			for m := k; m < j; m++ {
+				cls[idx[m]] = BidiEN
+			}
+		}
+		k = j - 1
+	}
+	// W6
+	for _, i := range idx {
+		switch cls[i] {
+		case BidiES, BidiET, BidiCS:
+			cls[i] = BidiON
+		}
+	}
+	// W7
+	strong = seq.sos
+	for _, i := range idx {
+		switch cls[i] {
+		case BidiL, BidiR:
+			strong = cls[i]
+		case BidiEN:
+			if strong == BidiL {
+				cls[i] = BidiL
+			}
+		}
+	}
+}
+
+// strongDirection maps a resolved class to L or R for rules N0–N2; ok is false
+// for neutrals.
+func strongDirection(c BidiClass) (BidiClass, bool) {
+	switch c {
+	case BidiL:
+		return BidiL, true
+	case BidiR, BidiEN, BidiAN:
+		return BidiR, true
+	}
+	return BidiON, false
+}
+
+// canonicalBracket maps brackets to their canonical equivalents (U+2329/U+232A
+// decompose to U+3008/U+3009).
+func canonicalBracket(r rune) rune {
+	switch r {
+	case 0x2329:
+		return 0x3008
+	case 0x232A:
+		return 0x3009
+	}
+	return r
+}
+
+// resolveBrackets implements rule N0 (paired brackets, BD16).
+func (p *bidiParagraphs) resolveBrackets(seq bidiSequence) {
+	idx := seq.indices
+	type opener struct {
+		closing rune
+		k       int // position in idx
+	}
+	var stack []opener
+	var pairs [][2]int
+scan:
+	for k, i := range idx {
+		if p.classes[i] != BidiON {
+			continue
+		}
+		r := canonicalBracket(p.runes[i])
+		if closing, ok := bidiBrackets[p.runes[i]]; ok {
+			if len(stack) == 63 {
+				break scan
+			}
+			stack = append(stack, opener{closing: canonicalBracket(closing), k: k})
+			continue
+		}
+		for s := len(stack) - 1; s >= 0; s-- {
+			if stack[s].closing == r {
+				pairs = append(pairs, [2]int{stack[s].k, k})
+				stack = stack[:s]
+				break
+			}
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a][0] < pairs[b][0] })
+
+	embedding := directionOf(seq.level)
+	for _, pair := range pairs {
+		var found, opposite bool
+		for k := pair[0] + 1; k < pair[1]; k++ {
+			if d, ok := strongDirection(p.classes[idx[k]]); ok {
+				if d == embedding {
+					found = true
+					break
+				}
+				opposite = true
+			}
+		}
+		var dir BidiClass
+		switch {
+		case found:
+			dir = embedding
+		case opposite:
+			context := seq.sos
+			for k := pair[0] - 1; k >= 0; k-- {
+				if d, ok := strongDirection(p.classes[idx[k]]); ok {
+					context = d
+					break
+				}
+			}
+			dir = embedding
+			if context != embedding {
+				dir = context
+			}
+		default:
+			continue
+		}
+		for _, k := range pair {
+			p.classes[idx[k]] = dir
+			// NSMs following a bracket take its direction.
+			for m := k + 1; m < len(idx) && p.orig[idx[m]] == BidiNSM; m++ {
+				p.classes[idx[m]] = dir
+			}
+		}
+	}
+}
+
+func isNeutralOrIsolate(c BidiClass) bool {
+	switch c {
+	case BidiB, BidiS, BidiWS, BidiON, BidiLRI, BidiRLI, BidiFSI, BidiPDI:
+		return true
+	}
+	return false
+}
+
+// resolveNeutral implements rules N1 and N2.
+func (p *bidiParagraphs) resolveNeutral(seq bidiSequence) {
+	idx := seq.indices
+	embedding := directionOf(seq.level)
+	for k := 0; k < len(idx); k++ {
+		if !isNeutralOrIsolate(p.classes[idx[k]]) {
+			continue
+		}
+		j := k
+		for j < len(idx) && isNeutralOrIsolate(p.classes[idx[j]]) {
+			j++
+		}
+		before, after := seq.sos, seq.eos
+		if k > 0 {
+			before, _ = strongDirection(p.classes[idx[k-1]])
+		}
+		if j < len(idx) {
+			after, _ = strongDirection(p.classes[idx[j]])
+		}
+		dir := embedding
+		if before == after {
+			dir = before
+		}
+		for m := k; m < j; m++ {
+			p.classes[idx[m]] = dir
+		}
+		k = j - 1
+	}
+}
+
+// resolveImplicit implements rules I1 and I2.
+func (p *bidiParagraphs) resolveImplicit(seq bidiSequence) {
+	for _, i := range seq.indices {
+		l := p.levels[i]
+		switch c := p.classes[i]; {
+		case !l.IsRTL() && c == BidiR:
+			p.levels[i]++
+		case !l.IsRTL() && (c == BidiAN || c == BidiEN):
+			p.levels[i] += 2
+		case l.IsRTL() && (c == BidiL || c == BidiEN || c == BidiAN):
+			p.levels[i]++
+		}
+	}
+}
+
+// resetWhitespace implements rule L1 for segment and paragraph separators and
+// the end of the paragraph, and gives the other characters removed by X9 the
+// level of the preceding character.
+func (p *bidiParagraphs) resetWhitespace(start, end int, paraLevel Level) {
+	for i := start + 1; i < end; i++ {
+		if p.removed[i] {
+			p.levels[i] = p.levels[i-1]
+		}
+	}
+	trailing := true // in whitespace preceding a separator or the end
+	for i := end - 1; i >= start; i-- {
+		switch c := p.orig[i]; {
+		case c == BidiB || c == BidiS:
+			p.levels[i] = paraLevel
+			trailing = true
+		case trailing && (c == BidiWS || isIsolateInitiator(c) || c == BidiPDI || isRemovedByX9(c)):
+			p.levels[i] = paraLevel
+		default:
+			trailing = false
+		}
+	}
+}
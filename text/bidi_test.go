@@ -0,0 +1,140 @@
+package text
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// runeLevels returns the levels of BidiLevels per rune, as a string of digits.
+func runeLevels(s string, dir Direction) string {
+	levels := BidiLevels(s, dir)
+	var out []byte
+	for i := range s {
+		out = append(out, byte('0'+levels[i]))
+	}
+	return string(out)
+}
+
+func TestLookupBidiClass(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want BidiClass
+	}{
+		{'a', BidiL}, {'א', BidiR}, {'ب', BidiAL}, {'1', BidiEN}, {'٣', BidiAN},
+		{' ', BidiWS}, {'(', BidiON}, {'\u0300', BidiNSM}, {'\u2067', BidiRLI}, {'\u202C', BidiPDF},
+		// Unicode 16.0 (Garay digits), and unassigned code points taking their block's default.
+		{'\U00010D40', BidiAN}, {'\u05FF', BidiR}, {'\U0001EC70', BidiAL}, {'\u2065', BidiBN},
+	}
+	for _, tt := range tests {
+		if got := LookupBidiClass(tt.r); got != tt.want {
+			t.Errorf("%U: expected class %d, got %d", tt.r, tt.want, got)
+		}
+	}
+}
+
+func TestBidiLevels(t *testing.T) {
+	tests := []struct {
+		text string
+		dir  Direction
+		want string
+	}{
+		{"abc", DirectionLTR, "000"},
+		{"אבג", DirectionLTR, "111"},
+		{"ab אב cd", DirectionLTR, "00011000"},
+		{"אב ab גד", DirectionRTL, "11122111"},
+		{"אב 12 גד", DirectionRTL, "11122111"},
+		{"ab 12", DirectionRTL, "22222"},
+		{"אב (cd) גד", DirectionRTL, "1111221111"},
+		{"ab (אב) cd", DirectionLTR, "0000110000"},
+		{"ب ١٢", DirectionRTL, "1122"},
+		{"ب 12", DirectionLTR, "1122"},
+		{"אב!", DirectionAuto, "111"},
+		{"!ab", DirectionAuto, "000"},
+		{"ab \u2067אב\u2069 cd", DirectionLTR, "0000110000"},
+		{"ab \u202Ecd\u202C ef", DirectionLTR, "0000111000"},
+		{"אב ", DirectionLTR, "110"},
+		{"ab ", DirectionRTL, "221"},
+	}
+	for _, tt := range tests {
+		if utf8.RuneCountInString(tt.text) != len(tt.want) {
+			t.Fatalf("bad test case %q: %d levels for %d runes", tt.text, len(tt.want), utf8.RuneCountInString(tt.text))
+		}
+		if got := runeLevels(tt.text, tt.dir); got != tt.want {
+			t.Errorf("%q (dir %d): expected levels %s, got %s", tt.text, tt.dir, tt.want, got)
+		}
+	}
+}
+
+// Rows in the format of BidiCharacterTest.txt, fields 1, 2 and 4: code points;
+// paragraph direction (0 LTR, 1 RTL, 2 auto); resolved levels, x for
+// characters removed by rule X9.
+var bidiCharacterTests = []string{
+	// Paired brackets (N0):
+	"0061 0028 0062 0029 05D0;1;2 2 2 2 1",
+	"05D0 0028 05D1 0029 0061;0;1 1 1 1 0",
+	"05D0 0028 0061 0029 05D1;0;1 0 0 0 1",
+	"0061 0028 05D0 0029 0062;1;2 1 1 1 2",
+	"05D0 0028 0031 0029;0;1 1 2 1",
+	"0061 0028 0029 05D0;1;2 1 1 1",
+	"05D0 0028 0061 005B 05D1 005D 0029;0;1 0 0 0 1 0 0",
+	"0061 0028 05D0 0029 0301 0062;1;2 1 1 1 1 2",
+	"05D0 2329 0061 3009 0062;1;1 1 2 1 2",
+	"0061 0028 0062 005D 05D0;1;2 2 2 1 1",
+	// Weak types (W1-W7):
+	"0628 0031 0032;0;1 2 2",
+	"05D0 0031 002C 0032;0;1 2 2 2",
+	"0628 0661 002C 0662;0;1 2 2 2",
+	"05D0 0020 0024 0031;0;1 1 2 2",
+	"0031 0025 0020 05D0;1;2 2 1 1",
+	"0061 0020 0031 002E 0032;1;2 2 2 2 2",
+	"05D0 002C 0031;0;1 1 2",
+	"0031 002B 0032 0020 05D0;0;0 0 0 0 1",
+	"0628 0300 0031;0;1 1 2",
+	"0031 002F 0032;1;2 2 2",
+	// Isolates and embeddings (X5a-X6a, X9):
+	"05D0 2066 0061 0062 2069 05D1;1;1 1 2 2 1 1",
+	"0061 2067 05D0 0020 0031 2069 0062;0;0 0 1 1 2 0 0",
+	"0061 2068 05D0 0062 2069 0063;0;0 0 1 2 0 0",
+	"0061 2068 0031 0062 2069 05D0;1;2 1 2 2 1 1",
+	"0061 2069 05D0;0;0 0 1",
+	"05D0 2066 0061;1;1 1 2",
+	"0061 202B 0062 202C 0063;0;0 x 2 x 0",
+	"05D0 2067 0031 2069;0;1 0 2 0",
+}
+
+func TestBidiLevels_CharacterTest(t *testing.T) {
+	dirs := []Direction{DirectionLTR, DirectionRTL, DirectionAuto}
+	for _, row := range bidiCharacterTests {
+		fields := strings.Split(row, ";")
+		if len(fields) != 3 {
+			t.Fatalf("bad test row %q", row)
+		}
+		var sb strings.Builder
+		for _, h := range strings.Fields(fields[0]) {
+			cp, err := strconv.ParseUint(h, 16, 32)
+			if err != nil {
+				t.Fatalf("bad code point in %q: %v", row, err)
+			}
+			sb.WriteRune(rune(cp))
+		}
+		s := sb.String()
+		d, err := strconv.Atoi(fields[1])
+		if err != nil || d < 0 || d >= len(dirs) {
+			t.Fatalf("bad direction in %q", row)
+		}
+		want := strings.Fields(fields[2])
+		levels := BidiLevels(s, dirs[d])
+		k := 0
+		for i := range s {
+			if k >= len(want) {
+				t.Fatalf("bad test row %q: too few levels", row)
+			}
+			if w := want[k]; w != "x" && w != strconv.Itoa(int(levels[i])) {
+				t.Errorf("%s: expected level %s at rune %d, got %d", fields[0], w, k, levels[i])
+			}
+			k++
+		}
+	}
+}
@@ -0,0 +1,60 @@
+#!/usr/bin/env python3
+"""Generates bidi_tables.go from the Unicode Character Database.
+
+Run `python3 gen_bidi_tables.py UCD > bidi_tables.go`, where UCD is a
+directory holding the files of https://www.unicode.org/Public/<version>/ucd/
+(extracted/DerivedBidiClass.txt and BidiBrackets.txt).
+"""
+import re
+import sys
+
+from gen_segment_tables import merge, read
+
+MISSING = re.compile(r"^#\s*@missing:\s*([0-9A-F]+)\.\.([0-9A-F]+)\s*;\s*(\w+)")
+
+
+def bidi_classes(ucd):
+    """Returns the Unicode version and the ranges whose Bidi_Class is not L.
+
+    Code points not listed take the default of the last @missing line covering
+    them, as unassigned code points in the right-to-left blocks are not L.
+    """
+    classes = ["L"] * 0x110000
+    with open("%s/extracted/DerivedBidiClass.txt" % ucd, encoding="utf-8") as f:
+        for line in f:
+            m = MISSING.match(line)
+            if m:
+                lo, hi = int(m.group(1), 16), int(m.group(2), 16)
+                classes[lo:hi + 1] = [m.group(3)] * (hi - lo + 1)
+    version, rows = read(ucd, "extracted/DerivedBidiClass.txt")
+    for lo, hi, f in rows:
+        classes[lo:hi + 1] = [f[0]] * (hi - lo + 1)
+    out = merge((cp, cp, c) for cp, c in enumerate(classes))
+    return version, [r for r in out if r[2] != "L"]
+
+
+def main():
+    if len(sys.argv) != 2:
+        sys.exit("usage: gen_bidi_tables.py UCD")
+    ucd = sys.argv[1]
+    version, classes = bidi_classes(ucd)
+    _, brackets = read(ucd, "BidiBrackets.txt")
+
+    w = sys.stdout.write
+    w("// Code generated by gen_bidi_tables.py from Unicode %s; DO NOT EDIT.\n\n" % version)
+    w("package text\n\n")
+    w("// bidiClasses lists the code point ranges whose Bidi_Class is not L.\n")
+    w("var bidiClasses = []bidiRange{\n")
+    for lo, hi, c in classes:
+        w("\t{0x%04X, 0x%04X, Bidi%s},\n" % (lo, hi, c))
+    w("}\n\n")
+    w("// bidiBrackets maps opening paired brackets to their Bidi_Paired_Bracket.\n")
+    w("var bidiBrackets = map[rune]rune{\n")
+    for lo, _, f in brackets:
+        if f[1] == "o":
+            w("\t0x%04X: 0x%s,\n" % (lo, f[0]))
+    w("}\n")
+
+
+if __name__ == "__main__":
+    main()
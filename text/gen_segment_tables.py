@@ -0,0 +1,91 @@
+#!/usr/bin/env python3
+"""Generates segment_tables.go from the Unicode Character Database.
+
+Run `python3 gen_segment_tables.py UCD > segment_tables.go`, where UCD is a
+directory holding the files of https://www.unicode.org/Public/<version>/ucd/
+(auxiliary/GraphemeBreakProperty.txt, auxiliary/WordBreakProperty.txt,
+auxiliary/SentenceBreakProperty.txt, emoji/emoji-data.txt and
+DerivedCoreProperties.txt).
+"""
+import os
+import re
+import sys
+
+LINE = re.compile(r"^([0-9A-F]+)(?:\.\.([0-9A-F]+))?\s*;\s*([^#]*?)\s*(?:#.*)?$")
+VERSION = re.compile(r"-(\d+\.\d+\.\d+)\.txt")
+
+
+def read(ucd, name):
+    """Returns the Unicode version of file name and its (lo, hi, fields) lines."""
+    version, rows = None, []
+    with open(os.path.join(ucd, name), encoding="utf-8") as f:
+        for line in f:
+            if version is None:
+                m = VERSION.search(line)
+                if m:
+                    version = m.group(1)
+            m = LINE.match(line.strip())
+            if not m:
+                continue
+            lo = int(m.group(1), 16)
+            hi = int(m.group(2), 16) if m.group(2) else lo
+            rows.append((lo, hi, [v.strip() for v in m.group(3).split(";")]))
+    return version, rows
+
+
+def merge(rows):
+    """Sorts ranges and joins adjacent ones of equal value."""
+    out = []
+    for lo, hi, v in sorted(rows):
+        if out and out[-1][1] + 1 == lo and out[-1][2] == v:
+            out[-1] = (out[-1][0], hi, v)
+        else:
+            out.append((lo, hi, v))
+    return out
+
+
+def property_table(w, ucd, name, var, typ, prefix, doc):
+    version, rows = read(ucd, name)
+    w("// %s\n" % doc)
+    w("var %s = []propRange[%s]{\n" % (var, typ))
+    for lo, hi, v in merge((lo, hi, f[0].replace("_", "")) for lo, hi, f in rows):
+        w("\t{0x%04X, 0x%04X, %s%s},\n" % (lo, hi, prefix, v))
+    w("}\n\n")
+    return version
+
+
+def main():
+    if len(sys.argv) != 2:
+        sys.exit("usage: gen_segment_tables.py UCD")
+    ucd = sys.argv[1]
+    out = []
+    w = out.append
+    version = property_table(w, ucd, "auxiliary/GraphemeBreakProperty.txt", "graphemeBreaks",
+                             "graphemeBreak", "gb",
+                             "graphemeBreaks lists the code point ranges whose Grapheme_Cluster_Break is not Other.")
+    property_table(w, ucd, "auxiliary/WordBreakProperty.txt", "wordBreaks", "wordBreak", "wb",
+                   "wordBreaks lists the code point ranges whose Word_Break is not Other.")
+    property_table(w, ucd, "auxiliary/SentenceBreakProperty.txt", "sentenceBreaks", "sentenceBreak", "sb",
+                   "sentenceBreaks lists the code point ranges whose Sentence_Break is not Other.")
+
+    _, rows = read(ucd, "emoji/emoji-data.txt")
+    w("// extendedPictographic lists the code point ranges with the Extended_Pictographic property.\n")
+    w("var extendedPictographic = []propRange[bool]{\n")
+    for lo, hi, _ in merge((lo, hi, True) for lo, hi, f in rows if f[0] == "Extended_Pictographic"):
+        w("\t{0x%04X, 0x%04X, true},\n" % (lo, hi))
+    w("}\n\n")
+
+    _, rows = read(ucd, "DerivedCoreProperties.txt")
+    w("// indicConjunctBreaks lists the code point ranges whose Indic_Conjunct_Break is not None.\n")
+    w("var indicConjunctBreaks = []propRange[indicConjunctBreak]{\n")
+    for lo, hi, v in merge((lo, hi, f[1]) for lo, hi, f in rows if f[0] == "InCB"):
+        w("\t{0x%04X, 0x%04X, incb%s},\n" % (lo, hi, v))
+    w("}\n")
+
+    sys.stdout.write("// Code generated by gen_segment_tables.py from Unicode %s; DO NOT EDIT.\n\n" % version)
+    sys.stdout.write("package text\n\n")
+    sys.stdout.write("".join(out))
+
+
+if __name__ == "__main__":
+    main()
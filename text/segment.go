@@ -0,0 +1,479 @@
+package text
+
+import "sort"
+
+//go:generate sh -c "python3 gen_segment_tables.py $UCD > segment_tables.go"
+
+// Text segmentation (UAX #29): boundaries of grapheme clusters, words and
+// sentences, following the default rules of the Unicode version of
+// segment_tables.go. The text of the range being segmented is the whole
+// text: its start and end are the sot and eot of the rules, and context
+// outside of it is not considered.
+
+// Boundaries iterates over the segment boundaries of a text range, in
+// increasing order. The start and the end of the range are boundaries, unless
+// the range is empty.
+type Boundaries struct {
+	start   TextPos
+	offsets []int // byte offset of each rune, plus the length of the text
+	seg     segmenter
+	i       int // rune index of the last boundary; -1 before the first
+}
+
+// segmenter finds the next boundary after the one before rune i.
+type segmenter interface {
+	next(i int) int
+}
+
+// GraphemeBoundaries returns the boundaries of the extended grapheme clusters
+// of range r of src.
+func GraphemeBoundaries(src TextReader, r TextRange) *Boundaries {
+	b, runes := newBoundaries(src, r)
+	g := &graphemeSegmenter{
+		gb:   make([]graphemeBreak, len(runes)),
+		incb: make([]indicConjunctBreak, len(runes)),
+		ep:   make([]bool, len(runes)),
+	}
+	for i, c := range runes {
+		g.gb[i] = lookupProp(graphemeBreaks, c)
+		g.incb[i] = lookupProp(indicConjunctBreaks, c)
+		g.ep[i] = lookupProp(extendedPictographic, c)
+	}
+	b.seg = g
+	return b
+}
+
+// WordBoundaries returns the word boundaries of range r of src. Segments
+// between them are words, runs of spaces, or single other characters.
+func WordBoundaries(src TextReader, r TextRange) *Boundaries {
+	b, runes := newBoundaries(src, r)
+	w := &wordSegmenter{wb: make([]wordBreak, len(runes)), ep: make([]bool, len(runes))}
+	for i, c := range runes {
+		w.wb[i] = lookupProp(wordBreaks, c)
+		w.ep[i] = lookupProp(extendedPictographic, c)
+	}
+	b.seg = w
+	return b
+}
+
+// SentenceBoundaries returns the sentence boundaries of range r of src.
+func SentenceBoundaries(src TextReader, r TextRange) *Boundaries {
+	b, runes := newBoundaries(src, r)
+	s := &sentenceSegmenter{sb: make([]sentenceBreak, len(runes))}
+	for i, c := range runes {
+		s.sb[i] = lookupProp(sentenceBreaks, c)
+	}
+	b.seg = s
+	return b
+}
+
+func newBoundaries(src TextReader, r TextRange) (*Boundaries, []rune) {
+	s := src.String(r)
+	b := &Boundaries{start: r.Start, i: -1}
+	runes := make([]rune, 0, len(s))
+	for off, c := range s {
+		b.offsets = append(b.offsets, off)
+		runes = append(runes, c)
+	}
+	b.offsets = append(b.offsets, len(s))
+	return b, runes
+}
+
+// Next returns the next boundary, or false after the end of the range.
+func (b *Boundaries) Next() (TextPos, bool) {
+	n := len(b.offsets) - 1
+	switch {
+	case n == 0 || b.i >= n:
+		return 0, false
+	case b.i < 0:
+		b.i = 0
+	default:
+		b.i = b.seg.next(b.i)
+	}
+	return b.start + TextPos(b.offsets[b.i]), true
+}
+
+// All returns the remaining boundaries.
+func (b *Boundaries) All() []TextPos {
+	var all []TextPos
+	for pos, ok := b.Next(); ok; pos, ok = b.Next() {
+		all = append(all, pos)
+	}
+	return all
+}
+
+// propRange assigns a property value to the code points Lo to Hi.
+type propRange[P any] struct {
+	Lo, Hi rune
+	Prop   P
+}
+
+// lookupProp returns the property value of r in table, or the zero value.
+func lookupProp[P any](table []propRange[P], r rune) P {
+	i := sort.Search(len(table), func(i int) bool { return table[i].Hi >= r })
+	if i < len(table) && table[i].Lo <= r {
+		return table[i].Prop
+	}
+	var zero P
+	return zero
+}
+
+// graphemeBreak is the Grapheme_Cluster_Break property of a character.
+type graphemeBreak uint8
+
+const (
+	gbOther graphemeBreak = iota
+	gbCR
+	gbLF
+	gbControl
+	gbExtend
+	gbZWJ
+	gbRegionalIndicator
+	gbPrepend
+	gbSpacingMark
+	gbL
+	gbV
+	gbT
+	gbLV
+	gbLVT
+)
+
+// indicConjunctBreak is the Indic_Conjunct_Break property of a character.
+type indicConjunctBreak uint8
+
+const (
+	incbNone indicConjunctBreak = iota
+	incbConsonant
+	incbExtend
+	incbLinker
+)
+
+type graphemeSegmenter struct {
+	gb   []graphemeBreak
+	incb []indicConjunctBreak
+	ep   []bool // Extended_Pictographic
+}
+
+func (g *graphemeSegmenter) next(i int) int {
+	// State of the cluster up to the character before j: the number of
+	// regional indicators in a row, whether it ends in \p{ExtPict} Extend*
+	// (ZWJ), and whether it ends in a consonant conjunct of GB9c.
+	ri := 0
+	emoji, emojiZWJ := g.ep[i], false
+	conjunct, linked := g.incb[i] == incbConsonant, false
+	if g.gb[i] == gbRegionalIndicator {
+		ri = 1
+	}
+	for j := i + 1; j < len(g.gb); j++ {
+		prev, cur := g.gb[j-1], g.gb[j]
+		var join bool
+		switch {
+		case prev == gbCR && cur == gbLF: // GB3
+			join = true
+		case isControlBreak(prev) || isControlBreak(cur): // GB4, GB5
+			join = false
+		case prev == gbL && (cur == gbL || cur == gbV || cur == gbLV || cur == gbLVT): // GB6
+			join = true
+		case (prev == gbLV || prev == gbV) && (cur == gbV || cur == gbT): // GB7
+			join = true
+		case (prev == gbLVT || prev == gbT) && cur == gbT: // GB8
+			join = true
+		case cur == gbExtend || cur == gbZWJ || cur == gbSpacingMark || prev == gbPrepend: // GB9–GB9b
+			join = true
+		case linked && g.incb[j] == incbConsonant: // GB9c
+			join = true
+		case emojiZWJ && g.ep[j]: // GB11
+			join = true
+		case prev == gbRegionalIndicator && cur == gbRegionalIndicator: // GB12, GB13
+			join = ri%2 == 1
+		}
+		if !join {
+			return j
+		}
+		if cur == gbRegionalIndicator {
+			ri++
+		} else {
+			ri = 0
+		}
+		emojiZWJ = emoji && cur == gbZWJ
+		emoji = g.ep[j] || emoji && cur == gbExtend
+		switch g.incb[j] {
+		case incbConsonant:
+			conjunct, linked = true, false
+		case incbLinker:
+			linked = conjunct
+		case incbNone:
+			conjunct, linked = false, false
+		}
+	}
+	return len(g.gb)
+}
+
+func isControlBreak(p graphemeBreak) bool {
+	return p == gbCR || p == gbLF || p == gbControl
+}
+
+// wordBreak is the Word_Break property of a character.
+type wordBreak uint8
+
+const (
+	wbOther wordBreak = iota
+	wbCR
+	wbLF
+	wbNewline
+	wbExtend
+	wbZWJ
+	wbRegionalIndicator
+	wbFormat
+	wbKatakana
+	wbHebrewLetter
+	wbALetter
+	wbSingleQuote
+	wbDoubleQuote
+	wbMidNumLet
+	wbMidLetter
+	wbMidNum
+	wbNumeric
+	wbExtendNumLet
+	wbWSegSpace
+)
+
+type wordSegmenter struct {
+	wb []wordBreak
+	ep []bool // Extended_Pictographic
+}
+
+func (w *wordSegmenter) next(i int) int {
+	ri := 0 // regional indicators in a row, ignoring WB4 characters
+	if w.wb[i] == wbRegionalIndicator {
+		ri = 1
+	}
+	for j := i + 1; j < len(w.wb); j++ {
+		if w.breaks(j, ri) {
+			return j
+		}
+		switch w.wb[j] {
+		case wbRegionalIndicator:
+			ri++
+		case wbExtend, wbFormat, wbZWJ:
+		default:
+			ri = 0
+		}
+	}
+	return len(w.wb)
+}
+
+// breaks tells whether there is a word boundary before character j, after ri
+// regional indicators.
+func (w *wordSegmenter) breaks(j, ri int) bool {
+	prev, cur := w.wb[j-1], w.wb[j]
+	switch {
+	case prev == wbCR && cur == wbLF: // WB3
+		return false
+	case isNewline(prev) || isNewline(cur): // WB3a, WB3b
+		return true
+	case prev == wbZWJ && w.ep[j]: // WB3c
+		return false
+	case prev == wbWSegSpace && cur == wbWSegSpace: // WB3d
+		return false
+	case cur == wbExtend || cur == wbFormat || cur == wbZWJ: // WB4
+		return false
+	}
+	// The remaining rules skip characters joined by WB4.
+	left := w.wb[w.prev(j)]
+	var before, after wordBreak = wbOther, wbOther
+	if p := w.prev(w.prev(j)); p >= 0 {
+		before = w.wb[p]
+	}
+	if n := w.following(j); n < len(w.wb) {
+		after = w.wb[n]
+	}
+	switch {
+	case isAHLetter(left) && isAHLetter(cur): // WB5
+		return false
+	case isAHLetter(left) && (cur == wbMidLetter || isMidNumLetQ(cur)) && isAHLetter(after): // WB6
+		return false
+	case isAHLetter(before) && (left == wbMidLetter || isMidNumLetQ(left)) && isAHLetter(cur): // WB7
+		return false
+	case left == wbHebrewLetter && cur == wbSingleQuote: // WB7a
+		return false
+	case left == wbHebrewLetter && cur == wbDoubleQuote && after == wbHebrewLetter: // WB7b
+		return false
+	case before == wbHebrewLetter && left == wbDoubleQuote && cur == wbHebrewLetter: // WB7c
+		return false
+	case (left == wbNumeric || isAHLetter(left)) && cur == wbNumeric: // WB8, WB9
+		return false
+	case left == wbNumeric && isAHLetter(cur): // WB10
+		return false
+	case before == wbNumeric && (left == wbMidNum || isMidNumLetQ(left)) && cur == wbNumeric: // WB11
+		return false
+	case left == wbNumeric && (cur == wbMidNum || isMidNumLetQ(cur)) && after == wbNumeric: // WB12
+		return false
+	case left == wbKatakana && cur == wbKatakana: // WB13
+		return false
+	case (isAHLetter(left) || left == wbNumeric || left == wbKatakana || left == wbExtendNumLet) &&
+		cur == wbExtendNumLet: // WB13a
+		return false
+	case left == wbExtendNumLet && (isAHLetter(cur) || cur == wbNumeric || cur == wbKatakana): // WB13b
+		return false
+	case left == wbRegionalIndicator && cur == wbRegionalIndicator: // WB15, WB16
+		return ri%2 == 0
+	}
+	return true // WB999
+}
+
+// prev returns the index of the character before j, skipping characters
+// joined to it by WB4, or -1 at the start of the text.
+func (w *wordSegmenter) prev(j int) int {
+	k := j - 1
+	for k >= 0 && isWordIgnorable(w.wb[k]) {
+		k--
+	}
+	if k < 0 || isNewline(w.wb[k]) {
+		// WB4 does not apply at the start of the text and after newlines.
+		return min(j-1, k+1)
+	}
+	return k
+}
+
+// following returns the index of the character after j, skipping characters
+// joined to j by WB4.
+func (w *wordSegmenter) following(j int) int {
+	k := j + 1
+	for k < len(w.wb) && isWordIgnorable(w.wb[k]) {
+		k++
+	}
+	return k
+}
+
+func isNewline(p wordBreak) bool {
+	return p == wbCR || p == wbLF || p == wbNewline
+}
+
+func isWordIgnorable(p wordBreak) bool {
+	return p == wbExtend || p == wbFormat || p == wbZWJ
+}
+
+func isAHLetter(p wordBreak) bool {
+	return p == wbALetter || p == wbHebrewLetter
+}
+
+func isMidNumLetQ(p wordBreak) bool {
+	return p == wbMidNumLet || p == wbSingleQuote
+}
+
+// sentenceBreak is the Sentence_Break property of a character.
+type sentenceBreak uint8
+
+const (
+	sbOther sentenceBreak = iota
+	sbCR
+	sbLF
+	sbExtend
+	sbSep
+	sbFormat
+	sbSp
+	sbLower
+	sbUpper
+	sbOLetter
+	sbNumeric
+	sbATerm
+	sbSContinue
+	sbSTerm
+	sbClose
+)
+
+type sentenceSegmenter struct {
+	sb []sentenceBreak
+}
+
+func (s *sentenceSegmenter) next(i int) int {
+	for j := i + 1; j < len(s.sb); j++ {
+		if s.breaks(j) {
+			return j
+		}
+	}
+	return len(s.sb)
+}
+
+// breaks tells whether there is a sentence boundary before character j.
+func (s *sentenceSegmenter) breaks(j int) bool {
+	prev, cur := s.sb[j-1], s.sb[j]
+	switch {
+	case prev == sbCR && cur == sbLF: // SB3
+		return false
+	case isParaSep(prev): // SB4
+		return true
+	case cur == sbExtend || cur == sbFormat: // SB5
+		return false
+	}
+	// The remaining rules skip characters joined by SB5.
+	p := s.prev(j)
+	left := s.sb[p]
+	switch {
+	case left == sbATerm && cur == sbNumeric: // SB6
+		return false
+	case left == sbATerm && cur == sbUpper:
+		if q := s.prev(p); q >= 0 && (s.sb[q] == sbUpper || s.sb[q] == sbLower) { // SB7
+			return false
+		}
+	}
+	// Match SATerm Close* Sp* before j.
+	k, spaces := p, false
+	for k >= 0 && s.sb[k] == sbSp {
+		k, spaces = s.prev(k), true
+	}
+	for k >= 0 && s.sb[k] == sbClose {
+		k = s.prev(k)
+	}
+	if k < 0 || !isSATerm(s.sb[k]) {
+		return false // SB998
+	}
+	switch {
+	case s.sb[k] == sbATerm && s.lowerFollows(j): // SB8
+		return false
+	case cur == sbSContinue || isSATerm(cur): // SB8a
+		return false
+	case !spaces && (cur == sbClose || cur == sbSp || isParaSep(cur)): // SB9
+		return false
+	case cur == sbSp || isParaSep(cur): // SB10
+		return false
+	}
+	return true // SB11
+}
+
+// lowerFollows tells whether the first character from j on which is not
+// OLetter, Upper, Lower, ParaSep or SATerm is Lower (rule SB8).
+func (s *sentenceSegmenter) lowerFollows(j int) bool {
+	for ; j < len(s.sb); j++ {
+		switch p := s.sb[j]; {
+		case p == sbLower:
+			return true
+		case p == sbOLetter || p == sbUpper || isParaSep(p) || isSATerm(p):
+			return false
+		}
+	}
+	return false
+}
+
+// prev returns the index of the character before j, skipping characters
+// joined to it by SB5, or -1 at the start of the text.
+func (s *sentenceSegmenter) prev(j int) int {
+	k := j - 1
+	for k >= 0 && (s.sb[k] == sbExtend || s.sb[k] == sbFormat) {
+		k--
+	}
+	if k < 0 || isParaSep(s.sb[k]) {
+		// SB5 does not apply at the start of the text and after separators.
+		return min(j-1, k+1)
+	}
+	return k
+}
+
+func isParaSep(p sentenceBreak) bool {
+	return p == sbSep || p == sbCR || p == sbLF
+}
+
+func isSATerm(p sentenceBreak) bool {
+	return p == sbATerm || p == sbSTerm
+}
@@ -0,0 +1,123 @@
+package text
+
+import (
+	"strings"
+	"testing"
+)
+
+// segments splits s at the boundaries returned by iter, joining the segments
+// with "|".
+func segments(s string, iter func(TextReader, TextRange) *Boundaries) string {
+	b := NewBuffer(1)
+	b.Append("<")
+	r := b.Append(s)
+	b.Append(">")
+	var parts []string
+	bounds := iter(b, r).All()
+	for i := 1; i < len(bounds); i++ {
+		parts = append(parts, b.String(TextRange{Start: bounds[i-1], End: bounds[i]}))
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestGraphemeBoundaries(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"ascii", "a|b|c"},
+		{"crlf", "a|\r\n|\n|b"},
+		{"combining", "e\u0301|x\u0308\u0301|!"},
+		{"control_then_mark", "\t|\u0301"},
+		{"hangul_jamo", "\u1100\u1161\u11a8|\u1100\u1100\uac00|a"},
+		{"hangul_syllables", "\uac01|\uac00\u11a8|\uac00"},
+		{"spacing_mark", "क\u093f|a"},
+		{"prepend", "\u0600a|b"},
+		{"conjunct", "क\u094dष\u093f|क"},
+		{"conjunct_zwj", "क\u094d\u200dष|a"},
+		{"no_conjunct_without_linker", "क\u093c|ष"},
+		{"emoji_zwj_sequence", "👩\u200d❤\ufe0f\u200d👨|a"},
+		{"zwj_without_emoji", "a\u200d|👨"},
+		{"flags", "🇩🇪|🇫🇷|🇮"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := strings.ReplaceAll(tt.want, "|", "")
+			if got := segments(s, GraphemeBoundaries); got != tt.want {
+				t.Errorf("expected %+q, got %+q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWordBoundaries(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"words", "The| |quick| |(|“|brown|”|)| |fox|."},
+		{"spaces", "a|   |b"},
+		{"apostrophe", "can't| |'|quote|'"},
+		{"mid_letter", "e.g|.| |a:b"},
+		{"numbers", "3.14| |1,000|,| |$|5"},
+		{"alphanumeric", "a1b2| |x_y| |_12"},
+		{"katakana", "カタカナ|ひ|ら"},
+		{"hebrew", "צה\"ל| |א'"},
+		{"extend", "a\u0301b| \u0301|x"},
+		{"format", "a\u00adb.\u00adc|,\u00ad|d"},
+		{"newlines", "a|\r\n|\n| |b"},
+		{"emoji_zwj", "👩\u200d👨| |a"},
+		{"flags", "🇩🇪|🇫🇷|🇮"},
+		{"han", "日|本|語"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := strings.ReplaceAll(tt.want, "|", "")
+			if got := segments(s, WordBoundaries); got != tt.want {
+				t.Errorf("expected %+q, got %+q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSentenceBoundaries(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"simple", "This is one. |This is two? |Three!"},
+		{"closing", "He said “Stop.” |Then left."},
+		{"abbreviation_lower", "Mr. smith is here. |Ok"},
+		{"number", "It costs 3.50 now."},
+		{"initials", "U.S.A. |Yes"},
+		{"paragraphs", "One\n|Two\r\n|Three |Four"},
+		{"terminator_run", "Wait?! |Yes."},
+		{"continue", "Hello, e.g., world."},
+		{"extend_after_separator", "a\n|\u0301b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := strings.ReplaceAll(tt.want, "|", "")
+			if got := segments(s, SentenceBoundaries); got != tt.want {
+				t.Errorf("expected %+q, got %+q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBoundaries_Positions(t *testing.T) {
+	b := NewBuffer(1)
+	b.Append("xx")
+	r := b.Append("né!")
+	got := GraphemeBoundaries(b, r).All()
+	want := []TextPos{2, 3, 5, 6}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if _, ok := WordBoundaries(b, TextRange{Start: 3, End: 3}).Next(); ok {
+		t.Fatalf("expected no boundaries in an empty range")
+	}
+}
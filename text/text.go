@@ -1,3 +1,8 @@
+// Package text holds references into source text and the Unicode algorithms
+// run on it: bidi levels per UAX #9 (BidiLevels) and the default grapheme,
+// word and sentence boundaries of UAX #29 (GraphemeBoundaries, WordBoundaries,
+// SentenceBoundaries). Their property tables are generated from Unicode data
+// by gen_bidi_tables.py and gen_segment_tables.py.
 package text
 
 type TextPos = uint64
@@ -13,3 +18,46 @@ type TextSource interface {
 	ID() TextSourceID
 	LenBytes() uint64
 }
+
+// TextReader is a TextSource whose text can be read back, e.g. for shaping.
+type TextReader interface {
+	TextSource
+	String(r TextRange) string
+}
+
+// TextStore is a TextSource which accepts additional text, e.g. generated content
+// created while building the layout tree.
+type TextStore interface {
+	TextSource
+	Append(s string) TextRange
+}
+
+// Buffer is a simple in-memory TextStore.
+type Buffer struct {
+	id   TextSourceID
+	data []byte
+}
+
+func NewBuffer(id TextSourceID) *Buffer {
+	return &Buffer{id: id}
+}
+
+func (b *Buffer) ID() TextSourceID { return b.id }
+
+func (b *Buffer) LenBytes() uint64 { return uint64(len(b.data)) }
+
+// Append adds s to the end of the buffer and returns the range it occupies.
+func (b *Buffer) Append(s string) TextRange {
+	start := TextPos(len(b.data))
+	b.data = append(b.data, s...)
+	return TextRange{Start: start, End: TextPos(len(b.data))}
+}
+
+// String returns the text of range r, clipped to the buffer.
+func (b *Buffer) String(r TextRange) string {
+	end := min(r.End, TextPos(len(b.data)))
+	if r.Start >= end {
+		return ""
+	}
+	return string(b.data[r.Start:end])
+}